@@ -33,6 +33,9 @@ func main() {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		log.Fatalf("failed to unmarshal config: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
 	configYaml, err := yaml.Marshal(cfg)
 	if err != nil {
 		log.Fatalf("failed to marshal config: %v", err)
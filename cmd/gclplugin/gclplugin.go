@@ -25,6 +25,9 @@ func New(settings any) (register.LinterPlugin, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &ErrStackPlugin{conf: conf}, nil
 }
@@ -44,7 +44,8 @@ func TestAnalyzer(t *testing.T) {
 	})
 
 	for _, f := range files {
-		if !f.IsDir() || f.Name() == "vendor" {
+		if !f.IsDir() || f.Name() == "vendor" || f.Name() == "golang.org" {
+			// Dependencies and their stubs are not test cases
 			continue
 		}
 
@@ -52,26 +53,75 @@ func TestAnalyzer(t *testing.T) {
 			dirPath, err := filepath.Abs(path.Join(testdata, "./src", f.Name()))
 			require.NoError(t, err)
 
+			var configFile []byte
 			configPath := path.Join(dirPath, ".errstack.yaml")
 			_, err = os.Stat(configPath)
 			if err == nil {
 				// A config file exists, use it
-				configFile, err := os.ReadFile(configPath)
-				require.NoError(t, err)
-
-				err = config.Analyzer.Flags.Set(config.YamlConfig, string(configFile))
+				configFile, err = os.ReadFile(configPath)
 				require.NoError(t, err)
 			} else if !os.IsNotExist(err) {
 				require.FailNow(t, err.Error())
 			}
 
-			r := analysistest.Run(t, testdata, errstack.Analyzer, f.Name())
-			res := r[0].Result
+			if !bothEngines[f.Name()] {
+				runAnalyzer(t, testdata, f.Name(), string(configFile))
+				return
+			}
+			for _, engine := range []string{config.EngineAST, config.EngineSSA} {
+				t.Run(engine, func(t *testing.T) {
+					runAnalyzer(t, testdata, f.Name(), "engine: "+engine+"\n"+string(configFile))
+				})
+			}
+		})
+	}
+}
 
-			result := res.(*helpers.Result[*errstack.Result])
-			require.NoError(t, result.Err)
+// bothEngines lists test cases run with each engine, their diagnostics and facts must not depend on the engine.
+var bothEngines = map[string]bool{
+	"interface_library":  true,
+	"func_value_library": true,
+}
+
+func runAnalyzer(t *testing.T, testdata, pkg, yamlConfig string) {
+	t.Helper()
+	if yamlConfig != "" {
+		err := config.Analyzer.Flags.Set(config.YamlConfig, yamlConfig)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = config.Analyzer.Flags.Set(config.YamlConfig, "")
 		})
 	}
+
+	r := analysistest.Run(t, testdata, errstack.Analyzer, pkg)
+	res := r[0].Result
+
+	result := res.(*helpers.Result[*errstack.Result])
+	require.NoError(t, result.Err)
+}
+
+func TestWorkspace(t *testing.T) {
+	// The workspace is a separate module tree with a go.work, so analysistest runs it in module mode
+	dir, err := filepath.Abs(path.Join(analysistest.TestData(), "workspace"))
+	require.NoError(t, err)
+	chdir(t, dir)
+	// Workspace mode rejects -mod=mod, which may be set in the environment
+	t.Setenv("GOFLAGS", "")
+
+	configFile, err := os.ReadFile(path.Join(dir, ".errstack.yaml"))
+	require.NoError(t, err)
+	err = config.Analyzer.Flags.Set(config.YamlConfig, string(configFile))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = config.Analyzer.Flags.Set(config.YamlConfig, "")
+	})
+
+	r := analysistest.Run(t, dir, errstack.Analyzer, "example.com/app")
+	require.GreaterOrEqual(t, len(r), 1)
+	res := r[0].Result
+
+	result := res.(*helpers.Result[*errstack.Result])
+	require.NoError(t, result.Err)
 }
 
 func TestConfig(t *testing.T) {
@@ -92,6 +142,49 @@ func TestConfig(t *testing.T) {
 	require.ElementsMatch(t, conf.CleanFunctions, config.DefaultCleanFunctions)
 }
 
+func TestConfigValidation(t *testing.T) {
+	for _, yamlConfig := range []string{
+		"engine: sa",
+		"unknownCallPolicy: warn",
+		"unwrapPolicy: must_",
+		"engine: ssa\nunknownCallPolicy: report",
+	} {
+		t.Run(yamlConfig, func(t *testing.T) {
+			_, err := config.NewConfig([]byte(yamlConfig))
+			require.Error(t, err)
+		})
+	}
+
+	conf, err := config.NewConfig([]byte("engine: ssa\nunknownCallPolicy: wrapping\nunwrapPolicy: keep"))
+	require.NoError(t, err)
+	require.Equal(t, config.EngineSSA, conf.Engine)
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	testdata := analysistest.TestData()
+	chdir(t, testdata+"/src")
+
+	for _, tc := range []string{"wrapper_helpers", "infer_wrappers"} {
+		t.Run(tc, func(t *testing.T) {
+			configFile, err := os.ReadFile(path.Join(testdata, "src", tc, ".errstack.yaml"))
+			if err != nil && !os.IsNotExist(err) {
+				require.FailNow(t, err.Error())
+			}
+			err = config.Analyzer.Flags.Set(config.YamlConfig, string(configFile))
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = config.Analyzer.Flags.Set(config.YamlConfig, "")
+			})
+
+			r := analysistest.RunWithSuggestedFixes(t, testdata, errstack.Analyzer, tc)
+			require.GreaterOrEqual(t, len(r), 1)
+
+			result := r[0].Result.(*helpers.Result[*errstack.Result])
+			require.NoError(t, result.Err)
+		})
+	}
+}
+
 func TestSingle(t *testing.T) {
 	testdata := analysistest.TestData()
 	chdir(t, testdata+"/src")
@@ -131,6 +224,29 @@ func TestAnalyzerPerformance(t *testing.T) {
 	}
 }
 
+func TestNestedCallbacksPerformance(t *testing.T) {
+	testdata := analysistest.TestData()
+
+	// Callbacks and closures nested in each other are walked once per state they run in,
+	// so nesting them deeply doesn't make the analysis exponentially slower
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		chdir(t, testdata+"/src")
+		analysistest.Run(t, testdata, errstack.Analyzer, "nested_callbacks")
+	}()
+
+	select {
+	case <-done:
+		// Test completed successfully
+	case <-ctx.Done():
+		t.Fatal("Test timed out - performance issue detected")
+	}
+}
+
 func TestFalsePositives(t *testing.T) {
 	// Test cases that should NOT trigger excessive warnings
 	testCases := []string{
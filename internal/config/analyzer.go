@@ -2,15 +2,12 @@ package config
 
 import (
 	"flag"
-	"fmt"
-	"os"
 	"reflect"
 
 	"github.com/AdamBrianBright/errstack/internal/helpers"
 	"github.com/AdamBrianBright/errstack/internal/log"
 
 	"golang.org/x/tools/go/analysis"
-	"gopkg.in/yaml.v3"
 )
 
 const _doc = `errstack_config analyzer is responsible to take configurations (flags) for ErrStack execution.
@@ -49,23 +46,10 @@ func newFlagSet() flag.FlagSet {
 func run(pass *analysis.Pass) (*Config, error) {
 	// Set up default values for the config.
 	conf := NewDefaultConfig()
-	defer func() {
-		wd, err := os.Getwd()
-		if err != nil {
-			panic(err)
-		}
-		goroot := os.Getenv("GOROOT")
-		conf.WorkDir = wd + "/"
-		conf.GoRoot = goroot + "/src/"
-	}()
 
 	if yamlConfig, ok := pass.Analyzer.Flags.Lookup(YamlConfig).Value.(flag.Getter).Get().(string); ok {
 		if len(yamlConfig) > 0 {
-			err := yaml.Unmarshal([]byte(yamlConfig), conf)
-			if err != nil {
-				return nil, fmt.Errorf("unmarshal config: %w", err)
-			}
-			return conf, nil
+			return NewConfig([]byte(yamlConfig))
 		}
 	}
 
@@ -1,5 +1,13 @@
 package config
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 var (
 	DefaultWrapperFunctions = []PkgFunctions{
 		{
@@ -22,12 +30,53 @@ var (
 			"Errorf",
 		}},
 	}
+	DefaultUnwrapFunctions = []PkgFunctions{
+		{Pkg: "github.com/pkg/errors", Names: []string{
+			"Cause", "Unwrap", "As",
+		}},
+		{Pkg: "errors", Names: []string{
+			"Unwrap", "As",
+		}},
+	}
 	DefaultExcludePatterns []string
+	DefaultSeverity        = Severity{
+		Always:    "error",
+		Sometimes: "warning",
+		Unknown:   "info",
+	}
 )
 
 const (
-	DefaultMaxDepth      = 100
-	DefaultIncludeVendor = true
+	// EngineAST walks function ASTs inside CFG blocks.
+	EngineAST = "ast"
+	// EngineSSA tracks error values through the SSA form of functions.
+	EngineSSA = "ssa"
+)
+
+const (
+	// UnknownCallClean treats errors of unresolved callees as clean.
+	UnknownCallClean = "clean"
+	// UnknownCallWrapping treats errors of unresolved callees as wrapped with stacktraces.
+	UnknownCallWrapping = "wrapping"
+	// UnknownCallReport reports wrapping of errors of unresolved callees as informational diagnostics.
+	UnknownCallReport = "report"
+)
+
+const (
+	// UnwrapMay assumes errors unwrapped from errors with stacktraces may have lost them.
+	UnwrapMay = "may"
+	// UnwrapKeep assumes errors unwrapped from errors with stacktraces keep them.
+	UnwrapKeep = "keep"
+	// UnwrapClean assumes unwrapped errors never carry stacktraces.
+	UnwrapClean = "clean"
+)
+
+const (
+	DefaultMaxDepth = 0
+	DefaultEngine   = EngineAST
+
+	DefaultUnknownCallPolicy = UnknownCallClean
+	DefaultUnwrapPolicy      = UnwrapMay
 )
 
 type Config struct {
@@ -38,23 +87,105 @@ type Config struct {
 	WrapperFunctions PkgsFunctions `mapstructure:"wrapperFunctions" yaml:"wrapperFunctions,omitempty"`
 	// CleanFunctions - a list of functions that are considered to clean errors without stacktrace.
 	CleanFunctions PkgsFunctions `mapstructure:"cleanFunctions" yaml:"cleanFunctions,omitempty"`
+	// ResetFunctions - a list of functions whose errors never carry stacktraces, whatever errors they are given,
+	// e.g. functions rebuilding errors from their messages at RPC boundaries. Unlike CleanFunctions,
+	// which only don't add stacktraces, stacktraces of their arguments are dropped.
+	ResetFunctions PkgsFunctions `mapstructure:"resetFunctions" yaml:"resetFunctions,omitempty"`
+	// UnwrapFunctions - a list of functions unwrapping the error given as their first argument: functions returning
+	// errors return the unwrapped error, e.g. errors.Unwrap and github.com/pkg/errors.Cause, other functions
+	// store it into the target given as their second argument, e.g. errors.As.
+	UnwrapFunctions PkgsFunctions `mapstructure:"unwrapFunctions" yaml:"unwrapFunctions,omitempty"`
+	// UnwrapPolicy - what to assume about stacktraces of unwrapped errors, either "may" (default) when they
+	// may have been dropped with the outer errors, "keep" when they never are or "clean" when they always are.
+	// Targets of stack types always carry stacktraces.
+	UnwrapPolicy string `mapstructure:"unwrapPolicy" yaml:"unwrapPolicy,omitempty"`
+	// StackTypes - a list of error types whose values carry stacktraces, e.g. custom error structs
+	// capturing runtime.Callers. Types with a StackTrace or Callers method are detected automatically.
+	StackTypes PkgsFunctions `mapstructure:"stackTypes" yaml:"stackTypes,omitempty"`
+	// InferWrappers - treat functions returning errors and capturing stacktraces with runtime.Callers,
+	// runtime.Caller, runtime.Stack or runtime/debug.Stack as wrapper functions, so error libraries
+	// work without being listed in WrapperFunctions.
+	InferWrappers bool `mapstructure:"inferWrappers" yaml:"inferWrappers,omitempty"`
+
+	// Engine - analysis engine to use, either "ast" (default) or "ssa".
+	// The SSA engine tracks error values through phi nodes, memory, closures and tuples uniformly.
+	Engine string `mapstructure:"engine" yaml:"engine,omitempty"`
+
+	// UnknownCallPolicy - what to assume about errors returned by callees that have neither source nor facts,
+	// either "clean" (default), "wrapping" or "report" to point out where the analysis is guessing.
+	// It also applies to interface methods outside of main packages, unless the interface has unexported methods,
+	// since importing packages may implement them. "report" is only supported by the AST engine.
+	UnknownCallPolicy string `mapstructure:"unknownCallPolicy" yaml:"unknownCallPolicy,omitempty"`
+
+	// Severity - categories of reported diagnostics, so CI can gate on errors that always
+	// have stacktraces while triaging errors that only sometimes have them.
+	Severity Severity `mapstructure:"severity" yaml:"severity,omitempty"`
 
 	// Performance tuning options
-	IncludeVendor   bool     `mapstructure:"includeVendor" yaml:"includeVendor,omitempty"`
 	ExcludePatterns []string `mapstructure:"excludePatterns" yaml:"excludePatterns,omitempty"`
-	MaxDepth        int      `mapstructure:"maxDepth" yaml:"maxDepth,omitempty"`
+	// MaxDepth - optional budget limiting the length of call chains followed while building the call graph.
+	// Zero means no limit; the call graph is solved to a fixpoint either way.
+	MaxDepth int `mapstructure:"maxDepth" yaml:"maxDepth,omitempty"`
 
-	GoRoot  string `mapstructure:"-" yaml:"-"`
-	WorkDir string `mapstructure:"-" yaml:"-"`
-	Debug   bool   `mapstructure:"__debug" yaml:"__debug,omitempty"`
+	Debug bool `mapstructure:"__debug" yaml:"__debug,omitempty"`
 }
 
 func NewDefaultConfig() *Config {
 	return &Config{
-		WrapperFunctions: DefaultWrapperFunctions,
-		CleanFunctions:   DefaultCleanFunctions,
-		IncludeVendor:    DefaultIncludeVendor,
-		ExcludePatterns:  DefaultExcludePatterns,
-		MaxDepth:         DefaultMaxDepth,
+		WrapperFunctions:  DefaultWrapperFunctions,
+		CleanFunctions:    DefaultCleanFunctions,
+		UnwrapFunctions:   DefaultUnwrapFunctions,
+		ExcludePatterns:   DefaultExcludePatterns,
+		MaxDepth:          DefaultMaxDepth,
+		Engine:            DefaultEngine,
+		UnknownCallPolicy: DefaultUnknownCallPolicy,
+		UnwrapPolicy:      DefaultUnwrapPolicy,
+		Severity:          DefaultSeverity,
+	}
+}
+
+// NewConfig returns the default config overridden by the config in yaml format.
+// Unknown values of options accepting a fixed set of values are rejected.
+func NewConfig(data []byte) (*Config, error) {
+	conf := NewDefaultConfig()
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// Validate returns an error if an option accepting a fixed set of values has any other value
+// or the engine does not support the chosen options. Empty values stand for the defaults.
+func (cfg *Config) Validate() error {
+	options := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"engine", cfg.Engine, []string{EngineAST, EngineSSA}},
+		{"unknownCallPolicy", cfg.UnknownCallPolicy, []string{UnknownCallClean, UnknownCallWrapping, UnknownCallReport}},
+		{"unwrapPolicy", cfg.UnwrapPolicy, []string{UnwrapMay, UnwrapKeep, UnwrapClean}},
+	}
+	for _, option := range options {
+		if option.value != "" && !slices.Contains(option.allowed, option.value) {
+			return fmt.Errorf("invalid %s %q, expected one of: %s", option.name, option.value, strings.Join(option.allowed, ", "))
+		}
+	}
+	if cfg.Engine == EngineSSA && cfg.UnknownCallPolicy == UnknownCallReport {
+		return fmt.Errorf("unknownCallPolicy %q is not supported by the %q engine", UnknownCallReport, EngineSSA)
+	}
+	return nil
+}
+
+// Severity - categories of diagnostics depending on how certain the existing stacktrace is.
+type Severity struct {
+	// Always - the wrapped error has a stacktrace on all paths.
+	Always string `mapstructure:"always" yaml:"always,omitempty"`
+	// Sometimes - the wrapped error has a stacktrace on some paths only.
+	Sometimes string `mapstructure:"sometimes" yaml:"sometimes,omitempty"`
+	// Unknown - nothing is known about the wrapped error, see UnknownCallPolicy.
+	Unknown string `mapstructure:"unknown" yaml:"unknown,omitempty"`
 }
@@ -1,23 +1,39 @@
 package config
 
 import (
+	"go/types"
 	"path/filepath"
 	"strings"
 )
 
-func (cfg *Config) GetPkgPath(dir string) string {
-	return cfg.GetDirPkgPath(filepath.Dir(dir))
+// PkgPath returns the import path of the package as it is written in import declarations.
+// Vendored packages are reported with their vendor directory prefix, which is trimmed,
+// so config entries match local, vendored and stdlib packages the same way.
+func (cfg *Config) PkgPath(pkg *types.Package) string {
+	if pkg == nil {
+		return ""
+	}
+	return trimVendor(pkg.Path())
 }
 
-func (cfg *Config) GetDirPkgPath(dir string) string {
-	if strings.HasPrefix(dir, cfg.WorkDir) {
-		dir = strings.TrimPrefix(dir, cfg.WorkDir)
-		dir = strings.TrimPrefix(dir, "vendor/")
-		return dir
-	}
-	if strings.HasPrefix(dir, cfg.GoRoot) {
-		return strings.TrimPrefix(dir, cfg.GoRoot)
+// IsExcluded returns true if the package path matches any of the exclude patterns.
+func (cfg *Config) IsExcluded(pkgPath string) bool {
+	for _, pattern := range cfg.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, pkgPath); matched {
+			return true
+		}
 	}
 
-	return dir
+	return false
+}
+
+// trimVendor trims everything up to the last vendor directory of the import path.
+func trimVendor(pkgPath string) string {
+	if strings.HasPrefix(pkgPath, "vendor/") {
+		pkgPath = strings.TrimPrefix(pkgPath, "vendor/")
+	}
+	if i := strings.LastIndex(pkgPath, "/vendor/"); i >= 0 {
+		pkgPath = pkgPath[i+len("/vendor/"):]
+	}
+	return pkgPath
 }
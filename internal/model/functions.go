@@ -3,19 +3,118 @@ package model
 import (
 	"go/ast"
 	"go/token"
+	"go/types"
 
 	"golang.org/x/tools/go/cfg"
 )
 
+// Key is the canonical identity of a function.
+// Declared functions and methods are identified by the origin of their *types.Func,
+// function values by the *types.Var holding them and function literals by their AST node.
+type Key struct {
+	Object types.Object     // Origin object of declared functions, methods and function values
+	Lit    *ast.FuncLit     // Function literal, which has no object
+	Recv   *types.TypeParam // Type parameter the Object method is called on
+}
+
+// ObjectKey returns the key of a declared function, method or function value.
+// Generic instantiations share the key of their origin.
+func ObjectKey(obj types.Object) Key {
+	switch o := obj.(type) {
+	case *types.Func:
+		return Key{Object: o.Origin()}
+	case *types.Var:
+		return Key{Object: o.Origin()}
+	}
+	return Key{Object: obj}
+}
+
+// TypeParamMethodKey returns the key of a method called on a value of the type parameter.
+func TypeParamMethodKey(method *types.Func, recv *types.TypeParam) Key {
+	return Key{Object: method.Origin(), Recv: recv}
+}
+
+// LitKey returns the key of a function literal.
+func LitKey(lit *ast.FuncLit) Key {
+	return Key{Lit: lit}
+}
+
 type Function struct {
-	Name       string           // Name of the function
-	Node       ast.Node         // AST node of the function
-	Type       *ast.FuncType    // Type of the function
-	Body       *ast.BlockStmt   // Body of the function
-	Block      *cfg.Block       // Control flow graph of the function
-	Pos        token.Position   // Position of the function declaration
-	IsWrapping bool             // Is true if this function returns wrapped errors
-	CalledBy   Stack[*Function] // Functions that call this function
-	Pkg        string           // Package containing the function
-	Info       *Info            // Info used to load the function
+	Name      string             // Name of the function
+	Key       Key                // Canonical identity of the function
+	Node      ast.Node           // AST node of the function
+	Type      *ast.FuncType      // Type of the function
+	Body      *ast.BlockStmt     // Body of the function
+	Block     *cfg.Block         // Control flow graph of the function
+	Pos       token.Position     // Position of the function declaration, only used for reporting
+	Verdict   Verdict            // Whether this function returns wrapped errors
+	Results   []Verdict          // Verdicts of errors returned as each result, nil if results were not analyzed separately
+	Fields    map[string]Verdict // Verdicts of errors returned in fields and elements of results, keyed by result index and path
+	Callbacks map[int]Callback   // Parameters holding callbacks whose errors are returned, keyed by parameter index
+	Yields    []Verdict          // Verdicts of errors yielded by the iterator the function is or returns, per yielded value
+	Outs      map[int]OutParam   // Pointer parameters the function writes errors through, keyed by parameter index
+	Wraps     []int              // Error parameters the function wraps with stacktraces, if it is a wrapper helper
+	CalledBy  Stack[*Function]   // Functions that call this function
+	Targets   Stack[*Function]   // Functions this function may dispatch to (e.g. interface implementations)
+	Partial   bool               // Whether the function may also dispatch to functions missing from Targets
+	Pkg       string             // Package containing the function
+	Info      *Info              // Info used to load the function
+}
+
+// ResultVerdict returns the verdict of the error returned as the i-th result.
+// Falls back to the verdict of the whole function if its results were not analyzed separately.
+func (f *Function) ResultVerdict(i int) Verdict {
+	if i < len(f.Results) {
+		return f.Results[i]
+	}
+	return f.Verdict
+}
+
+// YieldVerdict returns the verdict of the error yielded as the i-th value by the iterator the function is or returns.
+// Falls back to the verdict of the whole function if its yielded values were not analyzed separately.
+func (f *Function) YieldVerdict(i int) Verdict {
+	if i < len(f.Yields) {
+		return f.Yields[i]
+	}
+	return f.Verdict
+}
+
+// Callback describes how errors returned by a function passed as a parameter flow to the results
+// of the function calling it, e.g. retry and transaction helpers returning what their callback returns.
+type Callback struct {
+	Clean  []Verdict // Verdicts of results when the callback returns errors without stacktraces
+	Always []Verdict // Verdicts of results when the callback always returns errors with stacktraces
+}
+
+// Result returns the verdict of the i-th result when the callback returns errors with the given verdict.
+func (c Callback) Result(i int, callback Verdict) Verdict {
+	if i >= len(c.Clean) || i >= len(c.Always) {
+		return callback
+	}
+	return transfer(c.Clean[i], c.Always[i], callback)
+}
+
+// OutParam describes how the error a pointer parameter points to on return depends on the error
+// it pointed to when the function was called, e.g. helpers storing errors into *errp.
+type OutParam struct {
+	Clean  Verdict // Verdict of the error pointed to on return when it had no stacktrace on call
+	Always Verdict // Verdict of the error pointed to on return when it had a stacktrace on call
+}
+
+// Result returns the verdict of the error pointed to after the call, given the verdict of the error before it.
+func (o OutParam) Result(prev Verdict) Verdict {
+	return transfer(o.Clean, o.Always, prev)
+}
+
+// transfer returns the verdict of an output depending on an input with the given verdict,
+// knowing the output verdicts for inputs without stacktraces and with stacktraces on all paths.
+func transfer(clean, always, input Verdict) Verdict {
+	switch {
+	case input == Clean || clean == always:
+		return clean
+	case input == AlwaysWrapping:
+		return always
+	}
+	// Inputs sometimes carrying stacktraces or unknown errors are passed on some paths at least
+	return clean.Max(input)
 }
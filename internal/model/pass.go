@@ -12,6 +12,7 @@ import (
 
 type Info struct {
 	Fset  *token.FileSet
+	Pkg   *types.Package
 	Types *types.Info
 	Files []*ast.File
 }
@@ -19,6 +20,7 @@ type Info struct {
 func NewInfo(analysisPass *analysis.Pass) *Info {
 	return &Info{
 		Fset:  analysisPass.Fset,
+		Pkg:   analysisPass.Pkg,
 		Types: analysisPass.TypesInfo,
 		Files: analysisPass.Files,
 	}
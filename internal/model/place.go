@@ -0,0 +1,45 @@
+package model
+
+import (
+	"go/types"
+	"strings"
+)
+
+// Place is a variable or a part of it holding an error.
+// Struct fields are selected by their names and all elements of slices, arrays and maps
+// share a single place, e.g. `res.Err` is {res, ".Err"} and `errs[i]` is {errs, "[]"}.
+// Pointers are not followed, so `p.Err` is the same place whether p is a pointer or not.
+type Place struct {
+	Object types.Object // Variable holding the place, nil for temporary values
+	Path   string       // Selected fields and elements, empty for the variable itself
+}
+
+// VarPlace returns the place of the whole variable.
+func VarPlace(obj types.Object) Place {
+	return Place{Object: obj, Path: ""}
+}
+
+// Field returns the place of the struct field.
+func (p Place) Field(name string) Place {
+	return Place{Object: p.Object, Path: p.Path + "." + name}
+}
+
+// Elem returns the place of all elements of the slice, array or map.
+func (p Place) Elem() Place {
+	return Place{Object: p.Object, Path: p.Path + "[]"}
+}
+
+// IsElem reports whether the place is shared by multiple elements, so storing
+// into it never overwrites what other elements hold.
+func (p Place) IsElem() bool {
+	return strings.Contains(p.Path, "[]")
+}
+
+// Contains reports whether the other place is this place or a part of it.
+func (p Place) Contains(other Place) bool {
+	if p.Object != other.Object || !strings.HasPrefix(other.Path, p.Path) {
+		return false
+	}
+	rest := other.Path[len(p.Path):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
@@ -0,0 +1,54 @@
+package model
+
+// Verdict tells whether errors returned by a function carry stacktraces.
+// Verdicts are ordered by how certain a stacktrace is: Clean < Unknown < Wrapping < AlwaysWrapping.
+type Verdict uint8
+
+const (
+	Clean          Verdict = iota // Errors never carry stacktraces
+	Unknown                       // The analysis can't tell, e.g. the callee has neither source nor facts
+	Wrapping                      // Errors carry stacktraces on some paths
+	AlwaysWrapping                // Errors carry stacktraces on all paths
+)
+
+// Join merges verdicts of alternative paths. A stacktrace is only certain after the merge
+// if it is certain on all paths, otherwise a possible stacktrace is still possible.
+func (v Verdict) Join(other Verdict) Verdict {
+	if v == other {
+		return v
+	}
+	if v == AlwaysWrapping || other == AlwaysWrapping {
+		return Wrapping
+	}
+	return max(v, other)
+}
+
+// Max combines verdicts of errors that end up in the same error, e.g. an error wrapped
+// by a clean function still carries the stacktrace it had.
+func (v Verdict) Max(other Verdict) Verdict {
+	return max(v, other)
+}
+
+// May drops the certainty of the verdict, leaving a possible stacktrace possible.
+func (v Verdict) May() Verdict {
+	return min(v, Wrapping)
+}
+
+// IsWrapping reports whether errors carry stacktraces on some or all paths.
+func (v Verdict) IsWrapping() bool {
+	return v >= Wrapping
+}
+
+func (v Verdict) String() string {
+	switch v {
+	case Clean:
+		return "clean"
+	case Unknown:
+		return "unknown"
+	case Wrapping:
+		return "wrapping"
+	case AlwaysWrapping:
+		return "always wrapping"
+	}
+	return "invalid"
+}
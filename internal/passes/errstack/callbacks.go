@@ -0,0 +1,340 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// callbackParams returns indexes of parameters holding functions that return errors.
+func callbackParams(sig *types.Signature) []int {
+	var params []int
+	for i := 0; i < sig.Params().Len(); i++ {
+		if callback, ok := sig.Params().At(i).Type().Underlying().(*types.Signature); ok && hasErrorResult(callback) {
+			params = append(params, i)
+		}
+	}
+	return params
+}
+
+// analyzeCallbacks finds parameters of the function holding callbacks whose errors the function returns.
+// The function is walked once assuming each callback returns errors without stacktraces and once assuming
+// it always returns stacktraces; parameters changing the returned verdicts are the ones returned.
+func (res *Result) analyzeCallbacks(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	sig *types.Signature,
+) map[int]model.Callback {
+	var callbacks map[int]model.Callback
+	for _, i := range callbackParams(sig) {
+		param := sig.Params().At(i)
+		clean := res.probeCallback(pass, cfgs, fn, sig, param, model.Clean)
+		always := res.probeCallback(pass, cfgs, fn, sig, param, model.AlwaysWrapping)
+		if slices.Equal(clean, always) {
+			continue
+		}
+		log.Log("Function %s returns errors of callback %s: %v or %v\n", fn.Name, param.Name(), clean, always)
+		if callbacks == nil {
+			callbacks = make(map[int]model.Callback)
+		}
+		callbacks[i] = model.Callback{Clean: clean, Always: always}
+	}
+	return callbacks
+}
+
+// probeKey identifies results of a function walked while probing the callback parameter with the verdict.
+type probeKey struct {
+	fn      *model.Function
+	param   *types.Var
+	verdict model.Verdict
+}
+
+// probeCallback returns verdicts of results of the function assuming calls of the parameter
+// return errors with the given verdict. Nothing is reported.
+func (res *Result) probeCallback(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	sig *types.Signature,
+	param *types.Var,
+	verdict model.Verdict,
+) []model.Verdict {
+	// Effects of closures depend on the probed parameter
+	res.probes = map[*types.Var]model.Verdict{param: verdict}
+	clear(res.effects)
+	defer func() {
+		res.probes = nil
+		clear(res.effects)
+	}()
+	return res.probedResults(pass, cfgs, fn, sig)
+}
+
+// probedResults returns verdicts of results of the function walked while probing a callback parameter.
+// Functions are walked once per probed parameter and verdict, however many times they are called.
+func (res *Result) probedResults(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	sig *types.Signature,
+) []model.Verdict {
+	key := probeKey{fn: fn, param: nil, verdict: model.Clean}
+	for param, verdict := range res.probes {
+		key.param, key.verdict = param, verdict
+	}
+	if results, ok := res.probed[key]; ok {
+		return results
+	}
+	results := res.walkFunction(pass, cfgs, fn, sig).returns.results
+	res.probed[key] = results
+	return results
+}
+
+// probedCall returns the verdict assumed for errors returned by the call of a probed parameter.
+func (res *Result) probedCall(info *model.Info, call *ast.CallExpr) (model.Verdict, bool) {
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok || res.probes == nil {
+		return model.Clean, false
+	}
+	param, ok := info.Types.ObjectOf(id).(*types.Var)
+	if !ok {
+		return model.Clean, false
+	}
+	verdict, ok := res.probes[param]
+	return verdict, ok
+}
+
+// callbackResult returns the verdict of the i-th result of the call of a function returning errors of its
+// callbacks, given the callbacks passed. Reports false if none of the callbacks passed are known.
+func (res *Result) callbackResult(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	fn *model.Function,
+	call *ast.CallExpr,
+	i int,
+) (model.Verdict, bool) {
+	var verdict model.Verdict
+	var found bool
+	for _, param := range slices.Sorted(maps.Keys(fn.Callbacks)) {
+		if param >= len(call.Args) {
+			continue
+		}
+		callback := res.resolveFuncValue(info, cfgs, call.Args[param])
+		if callback == nil {
+			continue
+		}
+		returned := res.callbackVerdict(pass, cfgs, callback)
+		log.Log("Callback %s of %s returns %s\n", callback.Name, fn.Name, returned)
+		verdict = verdict.Max(fn.Callbacks[param].Result(i, returned))
+		found = true
+	}
+	return verdict, found
+}
+
+// callbackVerdict returns the verdict of errors returned by the callback as any of its results.
+// While probing callbacks, closures are walked again, since they may call the probed parameters.
+func (res *Result) callbackVerdict(pass *analysis.Pass, cfgs *ctrlflow.CFGs, fn *model.Function) model.Verdict {
+	results := fn.Results
+	if _, isLit := fn.Node.(*ast.FuncLit); isLit && res.probes != nil && fn.Block != nil {
+		if sig := signatureOf(fn); sig != nil {
+			results = res.probedResults(pass, cfgs, fn, sig)
+		}
+	}
+	if results == nil {
+		return fn.Verdict
+	}
+	var verdict model.Verdict
+	for _, result := range results {
+		verdict = verdict.Max(result)
+	}
+	return verdict
+}
+
+// SummarizeCallbacks finds parameters of package functions holding callbacks whose errors the functions return.
+// Functions passing their callbacks on to other such functions are found once those are, so the search
+// is repeated until nothing changes.
+func (e *SSAEngine) SummarizeCallbacks() {
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range e.funcs {
+			for _, i := range callbackParams(fn.Signature) {
+				if slices.Contains(e.callbacks[fn], i) || !e.returnsCallback(fn, fn.Params[i+paramOffset(fn)]) {
+					continue
+				}
+				log.Log("SSA function %s returns errors of parameter %d\n", fn.String(), i)
+				e.callbacks[fn] = append(e.callbacks[fn], i)
+				changed = true
+			}
+		}
+	}
+}
+
+// callbackProbe is the parameter searched for in errors returned by a function.
+type callbackProbe struct {
+	param  *ssa.Parameter
+	walked map[*ssa.Function]bool // Closures walked while probing, which may pass themselves on
+}
+
+// returnsCallback reports whether any error returned by the function may be returned by a call of the parameter.
+func (e *SSAEngine) returnsCallback(fn *ssa.Function, param *ssa.Parameter) bool {
+	e.probe = &callbackProbe{param: param, walked: map[*ssa.Function]bool{}}
+	defer func() { e.probe = nil }()
+	return e.returnsProbe(fn)
+}
+
+// returnsProbe reports whether any error returned by the function may be returned by a call of the probed parameter.
+func (e *SSAEngine) returnsProbe(fn *ssa.Function) bool {
+	if e.probe.walked[fn] {
+		return false
+	}
+	e.probe.walked[fn] = true
+	for _, block := range fn.Blocks {
+		ret, ok := block.Instrs[len(block.Instrs)-1].(*ssa.Return)
+		if !ok {
+			continue
+		}
+		for _, result := range ret.Results {
+			if isErrorType(result.Type()) && e.carriesStack(result, map[ssa.Value]bool{}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callReturnsProbe reports whether the call calls the probed parameter, or passes it on as a callback
+// whose errors are returned. Errors of any other calls are of no interest while probing.
+func (e *SSAEngine) callReturnsProbe(call *ssa.CallCommon) bool {
+	if call.IsInvoke() {
+		return false
+	}
+	if e.holdsProbe(call.Value, map[ssa.Value]bool{}) {
+		return true
+	}
+	for _, callee := range e.funcValues(call.Value, map[ssa.Value]bool{}) {
+		for _, arg := range e.callbackArgs(callee, call) {
+			if e.holdsProbe(arg, map[ssa.Value]bool{}) {
+				return true
+			}
+			for _, fn := range e.funcValues(arg, map[ssa.Value]bool{}) {
+				if fn.Parent() != nil && e.returnsProbe(fn) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// holdsProbe reports whether the value may be the probed parameter, directly or loaded from a variable
+// the parameter is stored to, e.g. when captured by a closure.
+func (e *SSAEngine) holdsProbe(v ssa.Value, visited map[ssa.Value]bool) bool {
+	if visited[v] {
+		return false
+	}
+	visited[v] = true
+	for _, alias := range e.aliases(v) {
+		if alias == e.probe.param {
+			return true
+		}
+	}
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return false
+	}
+	for _, addr := range e.aliases(load.X) {
+		refs := addr.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			if store, isStore := ref.(*ssa.Store); isStore && store.Addr == addr && e.holdsProbe(store.Val, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callbacksCarryStack reports whether any callback passed by the call to a function returning
+// errors of its callbacks may return an error with a stacktrace.
+func (e *SSAEngine) callbacksCarryStack(callee *ssa.Function, call *ssa.CallCommon) bool {
+	for _, arg := range e.callbackArgs(callee, call) {
+		for _, fn := range e.funcValues(arg, map[ssa.Value]bool{}) {
+			if e.summary(fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callbackArgs returns arguments of the call passed as parameters whose errors the callee returns.
+func (e *SSAEngine) callbackArgs(callee *ssa.Function, call *ssa.CallCommon) []ssa.Value {
+	var args []ssa.Value
+	for _, i := range e.callbackIndexes(callee) {
+		if i += paramOffset(callee); i < len(call.Args) {
+			args = append(args, call.Args[i])
+		}
+	}
+	return args
+}
+
+// callbackIndexes returns indexes of parameters whose errors the function returns,
+// found for package functions or imported from facts of other packages.
+func (e *SSAEngine) callbackIndexes(fn *ssa.Function) []int {
+	if fn.Origin() != nil {
+		fn = fn.Origin()
+	}
+	if fn.Synthetic == "" && fn.Pkg != nil && fn.Pkg.Pkg == e.pass.Pkg {
+		return e.callbacks[fn]
+	}
+	obj, ok := fn.Object().(*types.Func)
+	if !ok {
+		return nil
+	}
+	var fact WrappingFact
+	if !e.pass.ImportObjectFact(obj, &fact) {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(fact.Callbacks))
+}
+
+// callbackFacts describes parameters whose errors the function returns, as exported in facts.
+// Results return errors with stacktraces whenever callbacks do.
+func (e *SSAEngine) callbackFacts(fn *ssa.Function) map[int]model.Callback {
+	if len(e.callbacks[fn]) == 0 {
+		return nil
+	}
+	results := fn.Signature.Results()
+	clean := make([]model.Verdict, results.Len())
+	always := make([]model.Verdict, results.Len())
+	for i := range always {
+		if isErrorType(results.At(i).Type()) {
+			always[i] = model.Wrapping
+		}
+	}
+	callbacks := make(map[int]model.Callback, len(e.callbacks[fn]))
+	for _, i := range e.callbacks[fn] {
+		callbacks[i] = model.Callback{Clean: clean, Always: always}
+	}
+	return callbacks
+}
+
+// paramOffset returns the number of SSA parameters preceding the parameters of the signature, i.e. the receiver.
+func paramOffset(fn *ssa.Function) int {
+	if fn.Signature.Recv() != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package errstack
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+)
+
+// CallGraph is the graph of functions returning errors condensed into strongly connected components.
+// Components are ordered callees first, so every component is solved after all components it calls.
+type CallGraph struct {
+	Components [][]*model.Function
+	callees    map[*model.Function][]*model.Function
+	component  map[*model.Function]int
+}
+
+// NewCallGraph builds the call graph from the CalledBy edges of the functions and condenses it
+// into strongly connected components using Tarjan's algorithm.
+func NewCallGraph(functions map[*model.Function]bool) *CallGraph {
+	nodes := make([]*model.Function, 0, len(functions))
+	for fn := range functions {
+		nodes = append(nodes, fn)
+	}
+	// Map iteration order is random, keep the graph deterministic
+	slices.SortFunc(nodes, compareFunctions)
+
+	graph := &CallGraph{
+		callees:   make(map[*model.Function][]*model.Function, len(nodes)),
+		component: make(map[*model.Function]int, len(nodes)),
+	}
+	for _, fn := range nodes {
+		for _, caller := range fn.CalledBy {
+			if functions[caller] {
+				graph.callees[caller] = append(graph.callees[caller], fn)
+			}
+		}
+	}
+
+	var (
+		index   = 0
+		indices = make(map[*model.Function]int, len(nodes))
+		lowLink = make(map[*model.Function]int, len(nodes))
+		onStack = make(map[*model.Function]bool, len(nodes))
+		stack   = make(model.Stack[*model.Function], 0, len(nodes))
+	)
+	var connect func(fn *model.Function)
+	connect = func(fn *model.Function) {
+		indices[fn] = index
+		lowLink[fn] = index
+		index++
+		stack.Push(fn)
+		onStack[fn] = true
+
+		for _, callee := range graph.callees[fn] {
+			if _, ok := indices[callee]; !ok {
+				connect(callee)
+				lowLink[fn] = min(lowLink[fn], lowLink[callee])
+			} else if onStack[callee] {
+				lowLink[fn] = min(lowLink[fn], indices[callee])
+			}
+		}
+
+		if lowLink[fn] != indices[fn] {
+			return
+		}
+		var component []*model.Function
+		for member := stack.Pop(); member != nil; member = stack.Pop() {
+			onStack[*member] = false
+			graph.component[*member] = len(graph.Components)
+			component = append(component, *member)
+			if *member == fn {
+				break
+			}
+		}
+		graph.Components = append(graph.Components, component)
+	}
+	for _, fn := range nodes {
+		if _, ok := indices[fn]; !ok {
+			connect(fn)
+		}
+	}
+
+	return graph
+}
+
+// Solve combines the verdict of every function with the verdicts of functions it calls, except functions
+// matched by isClean. Components are solved callees first with a worklist inside every component, so
+// recursive and mutually recursive functions reach the exact fixpoint regardless of the length of call chains.
+func (g *CallGraph) Solve(isClean func(fn *model.Function) bool) {
+	for i, component := range g.Components {
+		worklist := make(model.Stack[*model.Function], 0, len(component))
+		for _, fn := range component {
+			worklist.Push(fn)
+		}
+		for item := worklist.Pop(); item != nil; item = worklist.Pop() {
+			fn := *item
+			if isClean(fn) {
+				continue
+			}
+			verdict := fn.Verdict.Max(g.calleesVerdict(fn))
+			if verdict == fn.Verdict {
+				continue
+			}
+			log.Log("Mark function %s.%s as %s: %s\n", fn.Pkg, fn.Name, verdict, fn.Pos.String())
+			fn.Verdict = verdict
+			// Only callers inside the same component may change now, the others are solved later
+			for _, caller := range fn.CalledBy {
+				if c, ok := g.component[caller]; ok && c == i && !caller.Verdict.IsWrapping() {
+					worklist.Push(caller)
+				}
+			}
+		}
+	}
+}
+
+// calleesVerdict combines verdicts of all functions the function calls. Calling a function
+// doesn't mean returning its errors, so stacktraces of callees are only possible in the caller.
+func (g *CallGraph) calleesVerdict(fn *model.Function) model.Verdict {
+	verdict := model.Clean
+	for _, callee := range g.callees[fn] {
+		verdict = verdict.Max(callee.Verdict.May())
+	}
+	return verdict
+}
+
+// compareFunctions orders functions by their position.
+func compareFunctions(a, b *model.Function) int {
+	return cmp.Or(
+		cmp.Compare(a.Pos.Filename, b.Pos.Filename),
+		cmp.Compare(a.Pos.Offset, b.Pos.Offset),
+		cmp.Compare(a.Name, b.Name),
+	)
+}
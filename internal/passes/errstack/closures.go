@@ -0,0 +1,208 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+	"maps"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+)
+
+// closureEffect is the state a closure leaves when run in the entry state, along with errors wrapped
+// by the closure, which are nil unless they were collected.
+type closureEffect struct {
+	entry   map[model.Place]model.Verdict
+	exit    map[model.Place]model.Verdict
+	wrapped map[*ast.CallExpr]*wrappedError
+}
+
+// localClosures describes closures held by local variables of a function that are only ever called.
+// Effects of such closures are applied where they are called instead of where they are created.
+type localClosures struct {
+	vars map[*types.Var][]*ast.FuncLit // Closures each variable may hold
+	held map[*ast.FuncLit]bool         // Closures held by the variables
+}
+
+// closuresOf returns closures held by local variables of the function that are only ever called.
+// Variables passed around, deferred or never called are left out, so their closures run where they are created.
+func (res *Result) closuresOf(info *model.Info, fn *model.Function) *localClosures {
+	if closures, ok := res.closures[fn.Node]; ok {
+		return closures
+	}
+	closures := &localClosures{
+		vars: make(map[*types.Var][]*ast.FuncLit),
+		held: make(map[*ast.FuncLit]bool),
+	}
+	res.closures[fn.Node] = closures
+	if fn.Body == nil {
+		return closures
+	}
+
+	assigned := make(map[*ast.Ident]bool)
+	addClosure := func(lhs ast.Expr, rhs ast.Expr) {
+		lit, isLit := ast.Unparen(rhs).(*ast.FuncLit)
+		id, isIdent := lhs.(*ast.Ident)
+		if !isLit || !isIdent {
+			return
+		}
+		if v, isVar := info.Types.ObjectOf(id).(*types.Var); isVar {
+			closures.vars[v] = append(closures.vars[v], lit)
+			assigned[id] = true
+		}
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) == len(node.Rhs) {
+				for i, lhs := range node.Lhs {
+					addClosure(lhs, node.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(node.Names) == len(node.Values) {
+				for i, name := range node.Names {
+					addClosure(name, node.Values[i])
+				}
+			}
+		}
+		return true
+	})
+
+	called := make(map[*types.Var]bool)
+	escaping := make(map[*types.Var]bool)
+	callees := make(map[*ast.Ident]bool)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.DeferStmt:
+			// Deferred calls run on return, not where they are deferred
+			if id, ok := ast.Unparen(node.Call.Fun).(*ast.Ident); ok {
+				if v, isVar := info.Types.Uses[id].(*types.Var); isVar {
+					escaping[v] = true
+				}
+			}
+		case *ast.CallExpr:
+			if id, ok := ast.Unparen(node.Fun).(*ast.Ident); ok {
+				callees[id] = true
+				if v, isVar := info.Types.Uses[id].(*types.Var); isVar {
+					called[v] = true
+				}
+			}
+		case *ast.Ident:
+			if v, isVar := info.Types.Uses[node].(*types.Var); isVar && !callees[node] && !assigned[node] {
+				escaping[v] = true
+			}
+		}
+		return true
+	})
+	for v, lits := range closures.vars {
+		if !called[v] || escaping[v] {
+			delete(closures.vars, v)
+			continue
+		}
+		for _, lit := range lits {
+			closures.held[lit] = true
+		}
+	}
+	return closures
+}
+
+// calledClosures returns closures the call may run: the called function literal
+// or closures held by the called variable.
+func (c *localClosures) calledClosures(info *model.Info, call *ast.CallExpr) []*ast.FuncLit {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.FuncLit:
+		return []*ast.FuncLit{fun}
+	case *ast.Ident:
+		if v, ok := info.Types.Uses[fun].(*types.Var); ok {
+			return c.vars[v]
+		}
+	}
+	return nil
+}
+
+// runClosures applies effects of the closures on captured variables to the state. Closures which must run
+// replace the state, while closures which may run at this point or any other, e.g. in goroutines or callbacks,
+// are joined with it. A variable holding one of several closures runs any of them.
+// Recursive calls of a closure being run are treated as closures which may run, keeping the state as is.
+func (res *Result) runClosures(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	fn *model.Function,
+	lits []*ast.FuncLit,
+	must bool,
+	variables map[model.Place]model.Verdict,
+	walk *functionWalk,
+) {
+	if len(lits) == 0 {
+		return
+	}
+	states := make([]map[model.Place]model.Verdict, 0, len(lits)+1)
+	if !must {
+		states = append(states, variables)
+	}
+	for _, lit := range lits {
+		if res.running[lit] {
+			log.Log("Closure %s is already running\n", info.Fset.Position(lit.Pos()))
+			res.recursed++
+			states = append(states, variables)
+			continue
+		}
+		// Closures returning errors are analyzed and reported on their own
+		collect := walk != nil && res.TryAddFunction(info, cfgs, lit) == nil
+		effect := res.closureEffect(pass, cfgs, info, fn, lit, variables, collect)
+		if collect {
+			for call, wrapped := range effect.wrapped {
+				walk.wrap(wrapped.wrapper, call, wrapped.verdict)
+			}
+		}
+		states = append(states, maps.Clone(effect.exit))
+	}
+	joined := joinStates(states...)
+	clear(variables)
+	maps.Copy(variables, joined)
+}
+
+// closureEffect returns the effect of running the closure in the state. Closures are walked once per state
+// they run in while a function is analyzed, so closures nested in closures are not walked again whenever
+// the closures holding them are. Effects depending on recursive calls of closures being run are not kept.
+func (res *Result) closureEffect(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	fn *model.Function,
+	lit *ast.FuncLit,
+	variables map[model.Place]model.Verdict,
+	collect bool,
+) closureEffect {
+	for _, effect := range res.effects[lit] {
+		if maps.Equal(effect.entry, variables) && (!collect || effect.wrapped != nil) {
+			return effect
+		}
+	}
+	log.Log("Running closure %s\n", info.Fset.Position(lit.Pos()))
+	var closureWalk *functionWalk
+	if collect {
+		closureWalk = &functionWalk{returns: nil, defers: nil, wrapped: map[*ast.CallExpr]*wrappedError{}}
+	}
+	recursed := res.recursed
+	res.running[lit] = true
+	state := res.runClosure(pass, cfgs, fn, lit, maps.Clone(variables), closureWalk)
+	delete(res.running, lit)
+	maps.DeleteFunc(state, func(place model.Place, _ model.Verdict) bool {
+		// Variables declared by the closure are not seen outside of it
+		return place.Object != nil && lit.Pos() <= place.Object.Pos() && place.Object.Pos() < lit.End()
+	})
+	effect := closureEffect{entry: maps.Clone(variables), exit: state, wrapped: nil}
+	if closureWalk != nil {
+		effect.wrapped = closureWalk.wrapped
+	}
+	if res.recursed == recursed {
+		res.effects[lit] = append(res.effects[lit], effect)
+	}
+	return effect
+}
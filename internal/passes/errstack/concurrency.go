@@ -0,0 +1,247 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// errorGroup describes a type running functions in goroutines and returning their errors from a method.
+type errorGroup struct {
+	Pkg  string   // Package of the type
+	Type string   // Name of the type
+	Go   []string // Methods running the function passed as the only argument
+	Wait string   // Method returning errors of the functions
+}
+
+// errorGroups are the known types collecting errors of goroutines.
+var errorGroups = []errorGroup{
+	{Pkg: "golang.org/x/sync/errgroup", Type: "Group", Go: []string{"Go", "TryGo"}, Wait: "Wait"},
+}
+
+// onceFunctions are functions returning a function that calls their argument once
+// and returns whatever it returned on every call.
+var onceFunctions = map[string][]string{
+	"sync": {"OnceValue", "OnceValues"},
+}
+
+// groupOf returns the error group the method belongs to, nil if it is a method of any other type.
+func (res *Result) groupOf(method *types.Func) *errorGroup {
+	if method == nil || method.Pkg() == nil {
+		return nil
+	}
+	recv := method.Signature().Recv()
+	if recv == nil {
+		return nil
+	}
+	typ := recv.Type()
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return nil
+	}
+	pkg := res.conf.PkgPath(method.Pkg())
+	for i := range errorGroups {
+		if errorGroups[i].Pkg == pkg && errorGroups[i].Type == named.Obj().Name() {
+			return &errorGroups[i]
+		}
+	}
+	return nil
+}
+
+// groupMethod returns the error group the called method belongs to, along with the place of the group value.
+func (res *Result) groupMethod(info *model.Info, call *ast.CallExpr) (*errorGroup, *types.Func, model.Place, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil, model.Place{}, false
+	}
+	method, _ := info.Types.ObjectOf(sel.Sel).(*types.Func)
+	group := res.groupOf(method)
+	if group == nil {
+		return nil, nil, model.Place{}, false
+	}
+	place, ok := placeOf(info, sel.X)
+	if !ok {
+		return nil, nil, model.Place{}, false
+	}
+	return group, method, place, true
+}
+
+// runInGroup records the error returned by the function passed to the Go method of an error group
+// as one of the errors held by the group, so Wait returns it later.
+func (res *Result) runInGroup(
+	info *model.Info,
+	cfgs *ctrlflow.CFGs,
+	call *ast.CallExpr,
+	variables map[model.Place]model.Verdict,
+) {
+	group, method, place, ok := res.groupMethod(info, call)
+	if !ok || !slices.Contains(group.Go, method.Name()) || len(call.Args) != 1 {
+		return
+	}
+	verdict := res.unknownVerdict()
+	if fn := res.resolveFuncValue(info, cfgs, call.Args[0]); fn != nil {
+		verdict = fn.ResultVerdict(0)
+	}
+	log.Log("Group %s runs function returning %s\n", placeName(place), verdict)
+	storePlace(variables, place.Elem(), verdict)
+}
+
+// waitForGroup returns the verdict of errors returned by the Wait method of an error group,
+// nil if the call is not such a method.
+func (res *Result) waitForGroup(info *model.Info, call *ast.CallExpr, variables map[model.Place]model.Verdict) *model.Verdict {
+	group, method, place, ok := res.groupMethod(info, call)
+	if !ok || method.Name() != group.Wait {
+		return nil
+	}
+	verdict := variables[place.Elem()]
+	log.Log("Group %s returns %s\n", placeName(place), verdict)
+	return &verdict
+}
+
+// onceArgument returns the function passed to the sync.OnceValues-like helper called, nil otherwise.
+func (res *Result) onceArgument(info *model.Info, call *ast.CallExpr) ast.Expr {
+	if len(call.Args) != 1 {
+		return nil
+	}
+	fun := ast.Unparen(call.Fun)
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		// Explicit instantiations
+		fun = index.X
+	case *ast.IndexListExpr:
+		fun = index.X
+	}
+	var id *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return nil
+	}
+	obj, _ := info.Types.ObjectOf(id).(*types.Func)
+	if !res.isOnceFunction(obj) {
+		return nil
+	}
+	return call.Args[0]
+}
+
+// isOnceFunction reports whether the function is one of the sync.OnceValues-like helpers.
+func (res *Result) isOnceFunction(obj *types.Func) bool {
+	if obj == nil || obj.Pkg() == nil {
+		return false
+	}
+	return slices.Contains(onceFunctions[res.conf.PkgPath(obj.Pkg())], obj.Name())
+}
+
+// sendValue records the value sent to the channel as one of its elements.
+func (res *Result) sendValue(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	send *ast.SendStmt,
+	variables map[model.Place]model.Verdict,
+) {
+	if !containsError(info.Types.TypeOf(send.Value)) {
+		return
+	}
+	place, ok := placeOf(info, send.Chan)
+	if !ok {
+		return
+	}
+	// Channels hold the values sent only, so elements are not joined with nil errors
+	dst := place.Elem()
+	if isNil(info, send.Value) {
+		storePlace(variables, dst, model.Clean)
+		return
+	}
+	for path, verdict := range res.valueParts(pass, cfgs, info, send.Value, variables) {
+		storePlace(variables, model.Place{Object: dst.Object, Path: dst.Path + path}, verdict)
+	}
+}
+
+// channelCarriesStack reports whether any value sent to the channel may carry a stacktrace.
+func (e *SSAEngine) channelCarriesStack(ch ssa.Value, visited map[ssa.Value]bool) bool {
+	for _, alias := range e.channelAliases(ch) {
+		refs := alias.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			if send, ok := ref.(*ssa.Send); ok && send.Chan == alias && e.carriesStack(send.X, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// channelAliases returns values holding the same channel. Channels captured by closures
+// are loaded from a shared variable, so all loads of it are aliases.
+func (e *SSAEngine) channelAliases(ch ssa.Value) []ssa.Value {
+	load, ok := ch.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return e.aliases(ch)
+	}
+	var aliases []ssa.Value
+	for _, addr := range e.aliases(load.X) {
+		visitSiblings(addr, func(sibling ssa.Instruction) {
+			if u, isLoad := sibling.(*ssa.UnOp); isLoad && u.Op == token.MUL {
+				aliases = append(aliases, e.aliases(u)...)
+			}
+		})
+	}
+	return aliases
+}
+
+// groupCarriesStack reports whether the call waits for an error group running functions
+// that may return errors with stacktraces. Reports false for calls of anything else.
+func (e *SSAEngine) groupCarriesStack(call *ssa.CallCommon) bool {
+	callee := call.StaticCallee()
+	if callee == nil || len(call.Args) == 0 {
+		return false
+	}
+	method, _ := callee.Object().(*types.Func)
+	group := e.res.groupOf(method)
+	if group == nil || method.Name() != group.Wait {
+		return false
+	}
+	for _, alias := range e.aliases(call.Args[0]) {
+		refs := alias.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			goCall, ok := ref.(ssa.CallInstruction)
+			if !ok || len(goCall.Common().Args) != 2 || goCall.Common().Args[0] != alias {
+				continue
+			}
+			goCallee := goCall.Common().StaticCallee()
+			if goCallee == nil {
+				continue
+			}
+			goMethod, _ := goCallee.Object().(*types.Func)
+			if e.res.groupOf(goMethod) != group || !slices.Contains(group.Go, goMethod.Name()) {
+				continue
+			}
+			for _, fn := range e.funcValues(goCall.Common().Args[1], map[ssa.Value]bool{}) {
+				if e.summary(fn) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
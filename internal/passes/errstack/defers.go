@@ -0,0 +1,177 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+	"maps"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/cfg"
+)
+
+// deferredClosure returns the function literal called by the defer statement, nil if it calls anything else.
+func deferredClosure(stmt *ast.DeferStmt) *ast.FuncLit {
+	if stmt.Call == nil {
+		return nil
+	}
+	lit, _ := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit)
+	return lit
+}
+
+// deferredClosures returns function literals deferred in the body, ignoring ones deferred by nested closures.
+func deferredClosures(body *ast.BlockStmt) []*ast.FuncLit {
+	if body == nil {
+		return nil
+	}
+	var defers []*ast.FuncLit
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.DeferStmt:
+			if lit := deferredClosure(node); lit != nil {
+				defers = append(defers, lit)
+				return false
+			}
+		case *ast.FuncLit:
+			return false
+		}
+		return true
+	})
+	return defers
+}
+
+// nestedClosures returns the outermost function literals in the body.
+func nestedClosures(body *ast.BlockStmt) []*ast.FuncLit {
+	if body == nil {
+		return nil
+	}
+	var closures []*ast.FuncLit
+	ast.Inspect(body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			closures = append(closures, lit)
+			return false
+		}
+		return true
+	})
+	return closures
+}
+
+// inClosure reports whether the node belongs to a closure created by the function instead of the function itself.
+func (w *functionWalk) inClosure(node ast.Node) bool {
+	for _, lit := range w.closures {
+		if lit.Pos() <= node.Pos() && node.End() <= lit.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// deferredAt returns closures deferred before the return statement in the order they were deferred.
+// Defer statements are matched by position, so closures deferred in branches not taken
+// on the way to the return statement are run as well.
+func (w *functionWalk) deferredAt(ret *ast.ReturnStmt) []*ast.FuncLit {
+	var defers []*ast.FuncLit
+	for _, lit := range w.defers {
+		if lit.Pos() < ret.Pos() {
+			defers = append(defers, lit)
+		}
+	}
+	return defers
+}
+
+// namedResults returns objects of the function results by their index, nil for unnamed results.
+// Returns nil if the function has no named results.
+func namedResults(info *model.Info, fn *model.Function) []types.Object {
+	if fn.Type == nil || fn.Type.Results == nil {
+		return nil
+	}
+	var (
+		objects []types.Object
+		named   bool
+	)
+	for _, field := range fn.Type.Results.List {
+		if len(field.Names) == 0 {
+			objects = append(objects, nil)
+			continue
+		}
+		for _, name := range field.Names {
+			objects = append(objects, info.Types.Defs[name])
+			named = true
+		}
+	}
+	if !named {
+		return nil
+	}
+	return objects
+}
+
+// runDefers assigns values returned by the return statement to named results and runs deferred closures
+// in reverse order, as Go does when the function returns. Returns the state after all closures have run
+// and indexes of named results returned as nil, which deferred closures usually leave alone.
+func (res *Result) runDefers(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	fn *model.Function,
+	ret *ast.ReturnStmt,
+	variables map[model.Place]model.Verdict,
+	defers []*ast.FuncLit,
+	walk *functionWalk,
+) (map[model.Place]model.Verdict, map[int]bool) {
+	state := maps.Clone(variables)
+	nils := make(map[int]bool)
+	if named := namedResults(info, fn); len(named) > 0 && len(ret.Results) > 0 {
+		lhs := make([]*model.Place, len(named))
+		for i, obj := range named {
+			if obj != nil && containsError(obj.Type()) {
+				place := model.VarPlace(obj)
+				lhs[i] = &place
+			}
+		}
+		if !res.assignResults(pass, cfgs, info, lhs, ret.Results, state) && len(ret.Results) == len(lhs) {
+			for i, place := range lhs {
+				if place == nil {
+					continue
+				}
+				nils[i] = isNil(info, ret.Results[i])
+				res.assignValue(pass, cfgs, info, *place, ret.Results[i], state)
+			}
+		}
+	}
+
+	// Deferred closures return nothing, so only wrapped errors are collected while walking them
+	deferred := &functionWalk{returns: nil, defers: nil, wrapped: walk.wrapped}
+	for i := len(defers) - 1; i >= 0; i-- {
+		log.Log("Running deferred closure %s\n", info.Fset.Position(defers[i].Pos()))
+		state = res.runClosure(pass, cfgs, fn, defers[i], state, deferred)
+	}
+	return state, nils
+}
+
+// runClosure walks the closure starting with the state of the function at the point the closure runs,
+// e.g. the return statement for deferred closures. Returns the state joined from all paths leaving the closure.
+func (res *Result) runClosure(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	lit *ast.FuncLit,
+	state map[model.Place]model.Verdict,
+	walk *functionWalk,
+) map[model.Place]model.Verdict {
+	graph := cfgs.FuncLit(lit)
+	if graph == nil || len(graph.Blocks) == 0 {
+		return state
+	}
+	blocks, in, out := res.solveBlocks(pass, cfgs, fn, graph.Blocks[0], state)
+	var exits []*cfg.Block
+	for _, block := range blocks {
+		res.analyzeOriginalFunctionBlock(pass, cfgs, fn, block, maps.Clone(in[block]), walk)
+		if len(block.Succs) == 0 {
+			exits = append(exits, block)
+		}
+	}
+	return joinVariables(exits, out)
+}
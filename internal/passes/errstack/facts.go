@@ -0,0 +1,130 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+)
+
+// WrappingFact is exported for every exported function returning errors and tells
+// importing packages whether the function returns errors with stacktraces.
+// Results and Fields hold verdicts of errors returned as each result and in fields of results,
+// if they were analyzed separately. Callbacks describe parameters whose errors are returned,
+// Yields hold verdicts of errors yielded by iterators and Outs describe pointer parameters
+// errors are written through. Inferred tells whether the function is inferred to be a wrapper
+// and Wraps lists error parameters wrapped with stacktraces by wrapper helpers.
+// Exported package-level error variables get a fact with the Verdict only.
+type WrappingFact struct {
+	Verdict   model.Verdict
+	Results   []model.Verdict
+	Fields    map[string]model.Verdict
+	Callbacks map[int]model.Callback
+	Yields    []model.Verdict
+	Outs      map[int]model.OutParam
+	Inferred  bool
+	Wraps     []int
+}
+
+func (*WrappingFact) AFact() {}
+
+func (f *WrappingFact) String() string {
+	return f.Verdict.String()
+}
+
+// ExportFacts exports wrapping facts for all exported functions and error variables declared in the analyzed package.
+// Unexported functions can't be called from other packages, so there is no need to export them.
+func (res *Result) ExportFacts(pass *analysis.Pass) {
+	for _, fn := range res.FunctionsWithErrors {
+		if _, ok := fn.Node.(*ast.FuncDecl); !ok {
+			continue
+		}
+		obj, ok := fn.Key.Object.(*types.Func)
+		if !ok || obj.Pkg() != pass.Pkg || !obj.Exported() {
+			continue
+		}
+		log.Log("Exporting fact for %s(%s): %s\n", fn.Name, fn.Verdict, fn.Pos.String())
+		pass.ExportObjectFact(obj, &WrappingFact{
+			Verdict:   fn.Verdict,
+			Results:   fn.Results,
+			Fields:    fn.Fields,
+			Callbacks: fn.Callbacks,
+			Yields:    fn.Yields,
+			Outs:      fn.Outs,
+			Wraps:     fn.Wraps,
+			Inferred:  res.isInferredWrapper(obj),
+		})
+	}
+	res.exportGlobalFacts(pass)
+}
+
+// TryAddObject tries to find the declaration of the function object and add it to the list of functions with errors.
+// Functions declared in other packages are added as virtual functions using facts exported by their packages.
+// Returns nil if the function does not return errors or its package was not analyzed.
+func (res *Result) TryAddObject(info *model.Info, cfgs *ctrlflow.CFGs, node ast.Node, obj *types.Func) *model.Function {
+	// Instantiations of generic functions are analyzed through their origin
+	obj = obj.Origin()
+	if obj.Pkg() == res.pass.Pkg {
+		if decl := res.findFuncDecl(info, obj); decl != nil {
+			return res.TryAddFunction(info, cfgs, decl)
+		}
+		return nil
+	}
+
+	key := model.ObjectKey(obj)
+	if v, ok := res.FunctionsWithErrors[key]; ok {
+		return v
+	}
+	pos := info.Fset.Position(obj.Pos())
+	var fact WrappingFact
+	if !res.pass.ImportObjectFact(obj, &fact) {
+		log.Log("No fact for function %s: %s\n", obj.FullName(), pos.String())
+		return nil
+	}
+
+	fn := &model.Function{
+		Name:      obj.Name(),
+		Key:       key,
+		Node:      node,
+		Type:      nil,
+		Body:      nil,
+		Block:     nil,
+		Pos:       pos,
+		Verdict:   fact.Verdict,
+		Results:   fact.Results,
+		Fields:    fact.Fields,
+		Callbacks: fact.Callbacks,
+		Yields:    fact.Yields,
+		Outs:      fact.Outs,
+		Wraps:     fact.Wraps,
+		CalledBy:  model.Stack[*model.Function]{},
+		Pkg:       res.conf.PkgPath(obj.Pkg()),
+		Info:      info,
+	}
+	res.FunctionsWithErrors[key] = fn
+	return fn
+}
+
+// findFuncDecl finds the declaration of the function object in the given files.
+// Declarations are indexed by their objects on the first lookup.
+func (res *Result) findFuncDecl(info *model.Info, obj *types.Func) *ast.FuncDecl {
+	if res.funcDecls == nil {
+		res.funcDecls = make(map[*types.Func]*ast.FuncDecl)
+		for _, f := range info.Files {
+			for _, d := range f.Decls {
+				decl, ok := d.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				if fn, isFunc := info.Types.Defs[decl.Name].(*types.Func); isFunc {
+					res.funcDecls[fn] = decl
+				}
+			}
+		}
+	}
+	return res.funcDecls[obj]
+}
@@ -0,0 +1,174 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+)
+
+// TryAddTypeParamMethod adds a virtual function for the method called on a value of the type parameter
+// and links it with the methods of all types the type parameter is instantiated with in the analyzed package.
+// If no instantiations are found, the method is resolved to implementations of the constraint instead.
+// Returns nil if the method does not return errors.
+func (res *Result) TryAddTypeParamMethod(
+	info *model.Info,
+	cfgs *ctrlflow.CFGs,
+	sel *ast.SelectorExpr,
+	tp *types.TypeParam,
+	method *types.Func,
+) *model.Function {
+	sig, ok := method.Type().(*types.Signature)
+	if !ok || !hasErrors(sig) {
+		return nil
+	}
+	tp = res.canonicalTypeParam(tp)
+	key := model.TypeParamMethodKey(method, tp)
+	if v, ok := res.FunctionsWithErrors[key]; ok {
+		return v
+	}
+	pos := info.Fset.Position(method.Pos())
+
+	fn := &model.Function{
+		Name:      method.Name(),
+		Key:       key,
+		Node:      sel,
+		Type:      nil,
+		Body:      nil,
+		Block:     nil,
+		Pos:       pos,
+		Verdict:   model.Clean,
+		Results:   nil,
+		Fields:    nil,
+		Callbacks: nil,
+		Yields:    nil,
+		Outs:      nil,
+		Wraps:     nil,
+		CalledBy:  model.Stack[*model.Function]{},
+		Targets:   model.Stack[*model.Function]{},
+		Partial:   false,
+		Pkg:       res.conf.PkgPath(method.Pkg()),
+		Info:      info,
+	}
+	res.FunctionsWithErrors[key] = fn
+
+	var targets []*model.Function
+	for _, arg := range res.typeArguments(info, tp) {
+		if target := res.resolveMethodOf(info, cfgs, sel, arg, method); target != nil {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 && isInterfaceMethod(method) {
+		fn.Partial = !res.knowsAllImplementations(method)
+		for _, impl := range res.findImplementations(method) {
+			if target := res.TryAddObject(info, cfgs, sel, impl); target != nil {
+				targets = append(targets, target)
+			} else {
+				fn.Partial = true
+			}
+		}
+	}
+	for _, target := range targets {
+		log.Log("Type parameter %s method %s may call %s: %s\n", tp.Obj().Name(), fn.Name, target.Name, target.Pos.String())
+		// The method returns whatever the methods of the instantiating types return
+		target.CalledBy.AddUnique(fn)
+		fn.Targets.AddUnique(target)
+	}
+	if len(targets) == 0 || fn.Partial {
+		// Neither instantiations nor all implementations are known
+		fn.Verdict = res.unknownVerdict()
+	}
+
+	return fn
+}
+
+// resolveMethodOf resolves the method with the name of the given method on the type argument.
+func (res *Result) resolveMethodOf(
+	info *model.Info,
+	cfgs *ctrlflow.CFGs,
+	sel *ast.SelectorExpr,
+	arg types.Type,
+	method *types.Func,
+) *model.Function {
+	if tp := typeParamOf(arg); tp != nil {
+		// Instantiated with a type parameter of another generic function
+		return res.TryAddTypeParamMethod(info, cfgs, sel, tp, method)
+	}
+	obj, _, _ := types.LookupFieldOrMethod(arg, true, method.Pkg(), method.Name())
+	impl, ok := obj.(*types.Func)
+	if !ok {
+		return nil
+	}
+	if isInterfaceMethod(impl) {
+		return res.TryAddInterfaceMethod(info, cfgs, sel, impl)
+	}
+	return res.TryAddObject(info, cfgs, sel, impl)
+}
+
+// typeArguments returns all type arguments the type parameter is instantiated with in the given files.
+func (res *Result) typeArguments(info *model.Info, tp *types.TypeParam) []types.Type {
+	var args []types.Type
+	for ident, inst := range info.Types.Instances {
+		var params *types.TypeParamList
+		switch obj := info.Types.ObjectOf(ident).(type) {
+		case *types.Func:
+			if sig, ok := obj.Origin().Type().(*types.Signature); ok {
+				params = sig.TypeParams()
+			}
+		case *types.TypeName:
+			if named, ok := types.Unalias(obj.Type()).(*types.Named); ok {
+				params = named.Origin().TypeParams()
+			}
+		}
+		if params == nil || tp.Index() >= params.Len() || params.At(tp.Index()) != tp {
+			continue
+		}
+		if tp.Index() < inst.TypeArgs.Len() {
+			args = append(args, inst.TypeArgs.At(tp.Index()))
+		}
+	}
+	return args
+}
+
+// canonicalTypeParam maps type parameters of generic method receivers to the type parameters
+// of the receiver type declaration, which are the ones instantiated by the callers.
+func (res *Result) canonicalTypeParam(tp *types.TypeParam) *types.TypeParam {
+	scope := res.pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() == 0 {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			sig, isSig := named.Method(i).Type().(*types.Signature)
+			if !isSig {
+				continue
+			}
+			recvParams := sig.RecvTypeParams()
+			if recvParams == nil || tp.Index() >= recvParams.Len() || recvParams.At(tp.Index()) != tp {
+				continue
+			}
+			return named.TypeParams().At(tp.Index())
+		}
+	}
+	return tp
+}
+
+// typeParamOf returns the type parameter of the type or of the pointer to it, nil otherwise.
+func typeParamOf(typ types.Type) *types.TypeParam {
+	if typ == nil {
+		return nil
+	}
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	tp, _ := typ.(*types.TypeParam)
+	return tp
+}
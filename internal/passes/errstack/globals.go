@@ -0,0 +1,189 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// isPackageVar reports whether the variable is declared at the package level.
+func isPackageVar(v *types.Var) bool {
+	return v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+}
+
+// placeVerdict returns the verdict of the error held in the place. Package-level variables
+// not assigned by the function yet hold whatever the package assigns to them.
+func (res *Result) placeVerdict(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	place model.Place,
+	variables map[model.Place]model.Verdict,
+) model.Verdict {
+	if verdict, ok := variables[place]; ok || place.Path != "" {
+		return verdict
+	}
+	if v, ok := place.Object.(*types.Var); ok && isPackageVar(v) {
+		return res.globalVerdict(pass, cfgs, info, v)
+	}
+	return model.Clean
+}
+
+// globalVerdict returns the verdict of errors held by the package-level variable: the join of the value it is
+// initialized with and all values assigned to it in the package, or the fact exported by the package declaring it.
+// Values are analyzed without the state of the functions assigning them, so local variables they refer to are clean.
+func (res *Result) globalVerdict(pass *analysis.Pass, cfgs *ctrlflow.CFGs, info *model.Info, v *types.Var) model.Verdict {
+	if verdict, ok := res.globals[v]; ok {
+		return verdict
+	}
+	if v.Pkg() != pass.Pkg {
+		var fact WrappingFact
+		if !pass.ImportObjectFact(v, &fact) {
+			// Variables of packages not analyzed, e.g. io.EOF, hold sentinel errors
+			res.globals[v] = model.Clean
+			return model.Clean
+		}
+		res.globals[v] = fact.Verdict
+		return fact.Verdict
+	}
+
+	// Values assigned from the variable itself add nothing to it
+	res.globals[v] = model.Clean
+	var verdicts []model.Verdict
+	initialized := false
+	assigned := func(lhs []ast.Expr, rhs []ast.Expr) {
+		for i, expr := range lhs {
+			if place, ok := placeOf(info, expr); !ok || place != model.VarPlace(v) {
+				continue
+			}
+			verdicts = append(verdicts, res.assignedVerdict(pass, cfgs, info, rhs, len(lhs), i))
+		}
+	}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.ValueSpec:
+				lhs := make([]ast.Expr, len(node.Names))
+				for i, name := range node.Names {
+					lhs[i] = name
+					initialized = initialized || (info.Types.Defs[name] == v && len(node.Values) > 0)
+				}
+				assigned(lhs, node.Values)
+			case *ast.AssignStmt:
+				assigned(node.Lhs, node.Rhs)
+			}
+			return true
+		})
+	}
+	if !initialized {
+		// Variables declared without values are nil until assigned
+		verdicts = append(verdicts, model.Clean)
+	}
+	verdict := verdicts[0]
+	for _, other := range verdicts[1:] {
+		verdict = verdict.Join(other)
+	}
+	log.Log("Package variable %s holds %s\n", v.Name(), verdict)
+	res.globals[v] = verdict
+	return verdict
+}
+
+// assignedVerdict returns the verdict of the error assigned as the i-th of the given number of values.
+func (res *Result) assignedVerdict(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	rhs []ast.Expr,
+	values int,
+	i int,
+) model.Verdict {
+	variables := map[model.Place]model.Verdict{}
+	if len(rhs) != values {
+		if results := res.analyzeCallResults(pass, cfgs, info, rhs, variables); i < len(results) {
+			return results[i]
+		}
+		return model.Clean
+	}
+	if isNil(info, rhs[i]) {
+		return model.Clean
+	}
+	if result := res.analyzeCallStack(pass, cfgs, info, rhs[i], variables); result != nil {
+		return *result
+	}
+	return model.Clean
+}
+
+// exportGlobalFacts exports wrapping facts for exported error variables declared in the analyzed package.
+func (res *Result) exportGlobalFacts(pass *analysis.Pass) {
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+	info := model.NewInfo(pass)
+	for _, v := range exportedErrorVars(pass.Pkg) {
+		verdict := res.globalVerdict(pass, cfgs, info, v)
+		log.Log("Exporting fact for variable %s(%s)\n", v.Name(), verdict)
+		pass.ExportObjectFact(v, &WrappingFact{Verdict: verdict})
+	}
+}
+
+// exportedErrorVars returns exported package-level variables of the package holding errors.
+func exportedErrorVars(pkg *types.Package) []*types.Var {
+	var vars []*types.Var
+	for _, name := range pkg.Scope().Names() {
+		if v, ok := pkg.Scope().Lookup(name).(*types.Var); ok && v.Exported() && isErrorType(v.Type()) {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// globalCarriesStack reports whether any value stored to the package-level variable may carry a stacktrace.
+// Package variables are initialized by the package init function, which is searched along with the package functions.
+func (e *SSAEngine) globalCarriesStack(global *ssa.Global, visited map[ssa.Value]bool) bool {
+	if global.Pkg == nil || global.Pkg.Pkg != e.pass.Pkg {
+		v, ok := global.Object().(*types.Var)
+		if !ok {
+			return false
+		}
+		var fact WrappingFact
+		return e.pass.ImportObjectFact(v, &fact) && fact.Verdict.IsWrapping()
+	}
+	if visited[global] {
+		return false
+	}
+	visited[global] = true
+
+	funcs := e.funcs
+	if init := e.pkg.Func("init"); init != nil {
+		funcs = append([]*ssa.Function{init}, funcs...)
+	}
+	for _, fn := range funcs {
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				if store, ok := instr.(*ssa.Store); ok && store.Addr == global && e.carriesStack(store.Val, visited) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// exportGlobalFacts exports wrapping facts for exported error variables declared in the package.
+func (e *SSAEngine) exportGlobalFacts() {
+	for _, v := range exportedErrorVars(e.pass.Pkg) {
+		global := e.pkg.Var(v.Name())
+		if global == nil {
+			continue
+		}
+		verdict := model.Clean
+		if e.globalCarriesStack(global, map[ssa.Value]bool{}) {
+			verdict = model.Wrapping
+		}
+		e.pass.ExportObjectFact(v, &WrappingFact{Verdict: verdict})
+	}
+}
@@ -0,0 +1,290 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// wrappedArgs returns arguments of the call wrapped with stacktraces: all arguments of wrapper functions
+// and the wrapped parameters of wrapper helpers. Returns nil for calls of any other functions.
+func (res *Result) wrappedArgs(fn *model.Function, call *ast.CallExpr) []ast.Expr {
+	if fn == nil {
+		return nil
+	}
+	if res.isWrapper(fn) {
+		return call.Args
+	}
+	var args []ast.Expr
+	for _, i := range fn.Wraps {
+		if i < len(call.Args) {
+			args = append(args, call.Args[i])
+		}
+	}
+	return args
+}
+
+// wrappedParams returns error parameters the function wraps with stacktraces, if it is a wrapper helper:
+// it returns stacktraces on all paths and passes the parameters on to wrapper functions or other helpers,
+// e.g. func wrap(err error, op string) error { return errors.Wrapf(err, "%s", op) }.
+func (res *Result) wrappedParams(cfgs *ctrlflow.CFGs, fn *model.Function, sig *types.Signature) []int {
+	if fn.Verdict != model.AlwaysWrapping || res.isWrapper(fn) {
+		return nil
+	}
+	params := make(map[types.Object]int)
+	for i := 0; i < sig.Params().Len(); i++ {
+		if isErrorType(sig.Params().At(i).Type()) {
+			params[sig.Params().At(i)] = i
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	wrapped := make(map[int]bool)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			for _, arg := range res.wrappedArgs(res.TryAddCallExpr(fn.Info, cfgs, node), node) {
+				id, ok := ast.Unparen(arg).(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if i, isParam := params[fn.Info.Types.ObjectOf(id)]; isParam {
+					wrapped[i] = true
+				}
+			}
+		}
+		return true
+	})
+	if len(wrapped) == 0 {
+		return nil
+	}
+	wraps := slices.Sorted(maps.Keys(wrapped))
+	log.Log("Function %s wraps parameters %v\n", fn.Name, wraps)
+	return wraps
+}
+
+// helperReplacement returns the call of a wrapper helper inlined with the wrapper function replaced
+// by its clean counterpart, e.g. wrap(err, "op") becomes errors.WithMessagef(err, "%s", "op") for
+// func wrap(err error, op string) error { return errors.Wrapf(err, "%s", op) }.
+// Only package functions returning a single call of a wrapper function are inlined, if they pass
+// each parameter on once and in order and everything else they pass means the same at the call.
+// Returns an empty string if the call can't be inlined or the wrapper has no replacement.
+func (res *Result) helperReplacement(cfgs *ctrlflow.CFGs, info *model.Info, call *ast.CallExpr) string {
+	obj, ok := typeutil.Callee(info.Types, call).(*types.Func)
+	if !ok || obj.Pkg() != info.Pkg {
+		return ""
+	}
+	decl := res.findFuncDecl(info, obj)
+	if decl == nil || decl.Recv != nil || decl.Type.TypeParams != nil || decl.Body == nil || len(decl.Body.List) != 1 {
+		return ""
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return ""
+	}
+	inner, ok := ast.Unparen(ret.Results[0]).(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	wrapper := res.TryAddCallExpr(info, cfgs, inner)
+	if wrapper == nil || !res.conf.WrapperFunctions.Match(wrapper.Pkg, wrapper.Name) {
+		return ""
+	}
+	scope := callScope(info, call)
+	if scope == nil || !resolvesAt(info, scope, call.Pos(), inner.Fun) {
+		return ""
+	}
+
+	sig := obj.Signature()
+	params := make(map[types.Object]int, sig.Params().Len())
+	for i := 0; i < sig.Params().Len(); i++ {
+		params[sig.Params().At(i)] = i
+	}
+	var args []string
+	ellipsis := inner.Ellipsis.IsValid()
+	next := 0
+	for i, arg := range inner.Args {
+		id, _ := ast.Unparen(arg).(*ast.Ident)
+		param, isParam := params[info.Types.Uses[id]]
+		if !isParam {
+			if !resolvesAt(info, scope, call.Pos(), arg) {
+				return ""
+			}
+			args = append(args, info.FormatNode(arg))
+			continue
+		}
+		if param != next || param >= len(call.Args) {
+			return ""
+		}
+		next++
+		if !sig.Variadic() || next < sig.Params().Len() {
+			args = append(args, info.FormatNode(call.Args[param]))
+			continue
+		}
+		// Variadic parameters are passed on as they were given
+		if !ellipsis || i != len(inner.Args)-1 {
+			return ""
+		}
+		for _, variadic := range call.Args[param:] {
+			args = append(args, info.FormatNode(variadic))
+		}
+		ellipsis = call.Ellipsis.IsValid()
+	}
+	if next != sig.Params().Len() || len(args) == 0 {
+		return ""
+	}
+
+	if len(inner.Args) == 1 {
+		return args[0]
+	}
+	text := info.FormatNode(inner.Fun) + "(" + strings.Join(args, ", ")
+	if ellipsis {
+		text += "..."
+	}
+	text += ")"
+	if len(inner.Args) == 2 {
+		return res.conf.WrapperFunctions.ReplaceWith(wrapper.Pkg, wrapper.Name, text)
+	}
+	return res.conf.WrapperFunctions.ReplaceWithFunction(wrapper.Pkg, wrapper.Name, text)
+}
+
+// callScope returns the innermost scope containing the call.
+func callScope(info *model.Info, call *ast.CallExpr) *types.Scope {
+	for _, file := range info.Files {
+		if file.FileStart <= call.Pos() && call.Pos() < file.FileEnd {
+			if scope := info.Types.Scopes[file]; scope != nil {
+				return scope.Innermost(call.Pos())
+			}
+		}
+	}
+	return nil
+}
+
+// resolvesAt reports whether identifiers of the expression refer to the same objects at the given position
+// of the scope, so the expression can be moved there. Imports resolve to the same package under the same name.
+func resolvesAt(info *model.Info, scope *types.Scope, pos token.Pos, expr ast.Expr) bool {
+	resolves := true
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncLit, *ast.CompositeLit:
+			// Function literals declare their own identifiers and keys of composite literals name fields
+			resolves = false
+		case *ast.SelectorExpr:
+			// Selected fields and methods depend on the selected value only
+			resolves = resolves && resolvesAt(info, scope, pos, node.X)
+			return false
+		case *ast.Ident:
+			obj := info.Types.Uses[node]
+			_, visible := scope.LookupParent(node.Name, pos)
+			if pkgName, isPkg := obj.(*types.PkgName); isPkg {
+				visiblePkg, isVisiblePkg := visible.(*types.PkgName)
+				resolves = resolves && isVisiblePkg && visiblePkg.Imported() == pkgName.Imported()
+			} else {
+				resolves = resolves && obj != nil && visible == obj
+			}
+		}
+		return resolves
+	})
+	return resolves
+}
+
+// SummarizeWrappers finds wrapper helpers among package functions returning stacktraces on all paths.
+// Helpers passing their parameters on to other helpers are found once those are, so the search
+// is repeated until nothing changes.
+func (e *SSAEngine) SummarizeWrappers() {
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range e.funcs {
+			if !e.always[fn] || e.wraps[fn] != nil {
+				continue
+			}
+			if obj, ok := fn.Object().(*types.Func); ok && e.isWrapper(obj) {
+				continue
+			}
+			if wraps := e.wrappedParams(fn); len(wraps) > 0 {
+				log.Log("SSA function %s wraps parameters %v\n", fn.String(), wraps)
+				e.wraps[fn] = wraps
+				changed = true
+			}
+		}
+	}
+}
+
+// wrappedParams returns indexes of error parameters of the function passed on to wrapper functions or helpers.
+func (e *SSAEngine) wrappedParams(fn *ssa.Function) []int {
+	wrapped := make(map[int]bool)
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			for _, arg := range e.wrappedArgs(call.Common()) {
+				param, isParam := unwrapInterface(arg).(*ssa.Parameter)
+				if !isParam || !isErrorType(param.Type()) {
+					continue
+				}
+				if i := slices.Index(fn.Params, param) - paramOffset(fn); i >= 0 {
+					wrapped[i] = true
+				}
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(wrapped))
+}
+
+// wrappedArgs returns arguments of the call wrapped with stacktraces: all arguments of wrapper functions
+// and the wrapped parameters of wrapper helpers, found for package functions or imported from facts.
+func (e *SSAEngine) wrappedArgs(call *ssa.CallCommon) []ssa.Value {
+	callee := call.StaticCallee()
+	if callee == nil {
+		return nil
+	}
+	if obj, ok := callee.Object().(*types.Func); ok && e.isWrapper(obj) {
+		return call.Args
+	}
+	if callee.Origin() != nil {
+		callee = callee.Origin()
+	}
+	wraps := e.wraps[callee]
+	if callee.Pkg == nil || callee.Pkg.Pkg != e.pass.Pkg {
+		var fact WrappingFact
+		if obj, isFunc := callee.Object().(*types.Func); isFunc && e.pass.ImportObjectFact(obj, &fact) {
+			wraps = fact.Wraps
+		}
+	}
+	var args []ssa.Value
+	for _, i := range wraps {
+		if i += paramOffset(callee); i < len(call.Args) {
+			args = append(args, call.Args[i])
+		}
+	}
+	return args
+}
+
+// unwrapInterface returns the value converted to an interface.
+func unwrapInterface(v ssa.Value) ssa.Value {
+	for {
+		switch value := v.(type) {
+		case *ssa.MakeInterface:
+			v = value.X
+		case *ssa.ChangeInterface:
+			v = value.X
+		default:
+			return v
+		}
+	}
+}
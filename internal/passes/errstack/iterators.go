@@ -0,0 +1,277 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// hasErrors reports whether the function returns errors in its results, yields them to loops
+// ranging over it, returns iterators yielding them or writes them through pointer parameters.
+func hasErrors(sig *types.Signature) bool {
+	if yieldSignature(sig) != nil || len(outParams(sig)) > 0 {
+		return true
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		if typ := sig.Results().At(i).Type(); containsError(typ) || yieldSignature(typ) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// yieldSignature returns the signature of the yield function of the iterator type, e.g. iter.Seq2[T, error],
+// nil if the type is not an iterator yielding errors.
+func yieldSignature(typ types.Type) *types.Signature {
+	if typ == nil {
+		return nil
+	}
+	sig, ok := typ.Underlying().(*types.Signature)
+	if !ok || sig.Params().Len() != 1 || sig.Results().Len() != 0 {
+		return nil
+	}
+	yield, ok := sig.Params().At(0).Type().Underlying().(*types.Signature)
+	if !ok || yield.Results().Len() != 1 || !types.Identical(yield.Results().At(0).Type(), types.Typ[types.Bool]) {
+		return nil
+	}
+	for i := 0; i < yield.Params().Len(); i++ {
+		if isErrorType(yield.Params().At(i).Type()) {
+			return yield
+		}
+	}
+	return nil
+}
+
+// iteratorSummary returns the summary collecting errors yielded by the iterator the function is or returns,
+// along with the yield parameter of the function. Returns nil if the function yields no errors.
+func iteratorSummary(sig *types.Signature) (*returnSummary, *types.Var) {
+	if yield := yieldSignature(sig); yield != nil {
+		return newReturnSummary(yield.Params().Len()), sig.Params().At(0)
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		if yield := yieldSignature(sig.Results().At(i).Type()); yield != nil {
+			return newReturnSummary(yield.Params().Len()), nil
+		}
+	}
+	return nil, nil
+}
+
+// collectYield adds verdicts of errors passed to the yield function of the iterator to the summary.
+func (res *Result) collectYield(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	call *ast.CallExpr,
+	variables map[model.Place]model.Verdict,
+	walk *functionWalk,
+) {
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok || walk.yield == nil || info.Types.ObjectOf(id) != walk.yield {
+		return
+	}
+	for i, arg := range call.Args {
+		if !isErrorType(info.Types.TypeOf(arg)) {
+			continue
+		}
+		if isNil(info, arg) {
+			// Nil errors carry nothing to wrap
+			continue
+		}
+		verdict := model.Clean
+		if result := res.analyzeCallStack(pass, cfgs, info, arg, variables); result != nil {
+			verdict = *result
+		}
+		log.Log("Yielding %s as value %d\n", verdict, i)
+		walk.yields.add(i, verdict)
+	}
+}
+
+// collectIterator adds verdicts of errors yielded by the returned iterator to the summary.
+func (res *Result) collectIterator(cfgs *ctrlflow.CFGs, info *model.Info, expr ast.Expr, walk *functionWalk) {
+	yield := yieldSignature(info.Types.TypeOf(expr))
+	if yield == nil || walk.yields == nil || isNil(info, expr) {
+		return
+	}
+	iterator := res.resolveFuncValue(info, cfgs, expr)
+	for i := 0; i < yield.Params().Len(); i++ {
+		if !isErrorType(yield.Params().At(i).Type()) {
+			continue
+		}
+		verdict := res.unknownVerdict()
+		if iterator != nil {
+			verdict = iterator.YieldVerdict(i)
+		}
+		walk.yields.add(i, verdict)
+	}
+}
+
+// assignYielded assigns errors yielded by the iterator ranged over to the variables of the loop.
+func (res *Result) assignYielded(
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	stmt *ast.RangeStmt,
+	variables map[model.Place]model.Verdict,
+) {
+	if yieldSignature(info.Types.TypeOf(stmt.X)) == nil {
+		return
+	}
+	var iterator *model.Function
+	if call, ok := ast.Unparen(stmt.X).(*ast.CallExpr); ok {
+		// Functions returning iterators summarize what their iterators yield
+		iterator = res.TryAddCallExpr(info, cfgs, call)
+	} else {
+		iterator = res.resolveFuncValue(info, cfgs, stmt.X)
+	}
+	for i, value := range []ast.Expr{stmt.Key, stmt.Value} {
+		if value == nil || !isErrorType(info.Types.TypeOf(value)) {
+			continue
+		}
+		dst, ok := placeOf(info, value)
+		if !ok {
+			continue
+		}
+		verdict := res.unknownVerdict()
+		if iterator != nil {
+			verdict = iterator.YieldVerdict(i)
+		}
+		log.Log("Iterator yields %s as %s\n", verdict, placeName(dst))
+		storePlace(variables, dst, verdict)
+	}
+}
+
+// yieldedCarriesStack reports whether the parameter of the body of a loop ranging over an iterator
+// may be an error with a stacktrace yielded by the iterator. Loop bodies are synthetic functions
+// passed as the yield function to the iterator.
+func (e *SSAEngine) yieldedCarriesStack(param *ssa.Parameter) bool {
+	body := param.Parent()
+	if body.Synthetic != "range-over-func yield" || body.Parent() == nil {
+		return false
+	}
+	index := slices.Index(body.Params, param)
+	for _, block := range body.Parent().Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || len(call.Call.Args) != 1 {
+				continue
+			}
+			if closure, isClosure := call.Call.Args[0].(*ssa.MakeClosure); !isClosure || closure.Fn != body {
+				continue
+			}
+			if e.iteratorCarriesStack(call.Call.Value, index, map[*ssa.Function]bool{}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// iteratorCarriesStack reports whether the iterator may yield an error with a stacktrace as the i-th value.
+func (e *SSAEngine) iteratorCarriesStack(v ssa.Value, i int, visited map[*ssa.Function]bool) bool {
+	if call, ok := v.(*ssa.Call); ok {
+		// Functions returning iterators summarize what their iterators yield
+		callee := call.Common().StaticCallee()
+		if callee == nil {
+			return e.res.unknownVerdict() == model.Wrapping
+		}
+		return e.yieldsCarryStack(callee, i, visited)
+	}
+	for _, fn := range e.funcValues(v, map[ssa.Value]bool{}) {
+		if e.yieldsCarryStack(fn, i, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// yieldsCarryStack reports whether the iterator the function is or returns may yield an error
+// with a stacktrace as the i-th value.
+func (e *SSAEngine) yieldsCarryStack(fn *ssa.Function, i int, visited map[*ssa.Function]bool) bool {
+	if fn.Origin() != nil {
+		fn = fn.Origin()
+	}
+	if obj, ok := fn.Object().(*types.Func); ok && fn.Synthetic != "" && e.objects[obj] != nil {
+		// Method values are wrappers of declared methods
+		fn = e.objects[obj]
+	}
+	if visited[fn] {
+		return false
+	}
+	visited[fn] = true
+	if fn.Pkg == nil || fn.Pkg.Pkg != e.pass.Pkg || len(fn.Blocks) == 0 {
+		obj, ok := fn.Object().(*types.Func)
+		if !ok {
+			return false
+		}
+		var fact WrappingFact
+		if !e.pass.ImportObjectFact(obj, &fact) {
+			return e.res.unknownVerdict() == model.Wrapping
+		}
+		if i < len(fact.Yields) {
+			return fact.Yields[i].IsWrapping()
+		}
+		return fact.Verdict.IsWrapping()
+	}
+
+	if yieldSignature(fn.Signature) != nil {
+		return e.yieldCallsCarryStack(fn, e.aliases(fn.Params[paramOffset(fn)]), i)
+	}
+	for _, block := range fn.Blocks {
+		ret, ok := block.Instrs[len(block.Instrs)-1].(*ssa.Return)
+		if !ok {
+			continue
+		}
+		for _, result := range ret.Results {
+			if yieldSignature(result.Type()) != nil && e.iteratorCarriesStack(result, i, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// yieldCallsCarryStack reports whether any call of the yield function in the function or its closures
+// may pass an error with a stacktrace as the i-th value.
+func (e *SSAEngine) yieldCallsCarryStack(fn *ssa.Function, yields []ssa.Value, i int) bool {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || i >= len(call.Call.Args) || !slices.Contains(yields, call.Call.Value) {
+				continue
+			}
+			if e.carriesStack(call.Call.Args[i], map[ssa.Value]bool{}) {
+				return true
+			}
+		}
+	}
+	for _, anon := range fn.AnonFuncs {
+		if e.yieldCallsCarryStack(anon, yields, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// yieldFacts returns verdicts of errors yielded by the iterator the function is or returns, as exported in facts.
+func (e *SSAEngine) yieldFacts(fn *ssa.Function) []model.Verdict {
+	yield := yieldSignature(fn.Signature)
+	for j := 0; yield == nil && j < fn.Signature.Results().Len(); j++ {
+		yield = yieldSignature(fn.Signature.Results().At(j).Type())
+	}
+	if yield == nil {
+		return nil
+	}
+	yields := make([]model.Verdict, yield.Params().Len())
+	for i := range yields {
+		if isErrorType(yield.Params().At(i).Type()) && e.yieldsCarryStack(fn, i, map[*ssa.Function]bool{}) {
+			yields[i] = model.Wrapping
+		}
+	}
+	return yields
+}
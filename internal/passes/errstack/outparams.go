@@ -0,0 +1,145 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/ssa"
+)
+
+// outParams returns indexes of parameters pointing to errors, which the function may write errors through.
+func outParams(sig *types.Signature) []int {
+	var params []int
+	for i := 0; i < sig.Params().Len(); i++ {
+		if ptr, ok := sig.Params().At(i).Type().Underlying().(*types.Pointer); ok && isErrorType(ptr.Elem()) {
+			params = append(params, i)
+		}
+	}
+	return params
+}
+
+// analyzeOutParams finds pointer parameters the function writes errors through. The function is solved once
+// assuming the error pointed to has no stacktrace and once assuming it always has one; parameters whose
+// errors on return are not just the errors they pointed to are the ones returned.
+func (res *Result) analyzeOutParams(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	sig *types.Signature,
+) map[int]model.OutParam {
+	var outs map[int]model.OutParam
+	for _, i := range outParams(sig) {
+		param := sig.Params().At(i)
+		clean := res.writtenVerdict(pass, cfgs, fn, param, model.Clean)
+		always := res.writtenVerdict(pass, cfgs, fn, param, model.AlwaysWrapping)
+		if clean == model.Clean && always == model.AlwaysWrapping {
+			continue
+		}
+		log.Log("Function %s writes errors through %s: %s or %s\n", fn.Name, param.Name(), clean, always)
+		if outs == nil {
+			outs = make(map[int]model.OutParam)
+		}
+		outs[i] = model.OutParam{Clean: clean, Always: always}
+	}
+	return outs
+}
+
+// writtenVerdict returns the verdict of the error the parameter points to when the function returns,
+// assuming it pointed to an error with the given verdict when the function was called. Nothing is reported.
+func (res *Result) writtenVerdict(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	param *types.Var,
+	verdict model.Verdict,
+) model.Verdict {
+	place := model.VarPlace(param)
+	blocks, _, out := res.solveBlocks(pass, cfgs, fn, fn.Block, map[model.Place]model.Verdict{place: verdict})
+	exits := slices.DeleteFunc(slices.Clone(blocks), func(block *cfg.Block) bool {
+		return len(block.Succs) > 0
+	})
+	return joinVariables(exits, out)[place]
+}
+
+// writeOutParams updates errors passed by pointer to a function writing errors through its parameters.
+func (res *Result) writeOutParams(
+	info *model.Info,
+	cfgs *ctrlflow.CFGs,
+	call *ast.CallExpr,
+	variables map[model.Place]model.Verdict,
+) {
+	fn := res.TryAddCallExpr(info, cfgs, call)
+	if fn == nil || len(fn.Outs) == 0 {
+		return
+	}
+	for _, i := range slices.Sorted(maps.Keys(fn.Outs)) {
+		if i >= len(call.Args) {
+			continue
+		}
+		arg := ast.Unparen(call.Args[i])
+		if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			// &err points to the place of err
+			arg = ast.Unparen(unary.X)
+		}
+		place, ok := placeOf(info, arg)
+		if !ok {
+			continue
+		}
+		verdict := fn.Outs[i].Result(variables[place])
+		log.Log("Function %s writes %s through %s\n", fn.Name, verdict, placeName(place))
+		storePlace(variables, place, verdict)
+	}
+}
+
+// outCarriesStack reports whether the callee may write an error with a stacktrace through
+// the address passed as the i-th argument of the call.
+func (e *SSAEngine) outCarriesStack(call *ssa.CallCommon, i int, visited map[ssa.Value]bool) bool {
+	callee := call.StaticCallee()
+	if callee == nil {
+		return false
+	}
+	if callee.Origin() != nil {
+		callee = callee.Origin()
+	}
+	if callee.Pkg != nil && callee.Pkg.Pkg == e.pass.Pkg && len(callee.Blocks) > 0 {
+		if i >= len(callee.Params) || visited[callee.Params[i]] {
+			return false
+		}
+		visited[callee.Params[i]] = true
+		return e.addressCarriesStack(callee.Params[i], nil, visited)
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return false
+	}
+	var fact WrappingFact
+	if !e.pass.ImportObjectFact(obj, &fact) {
+		return false
+	}
+	out, ok := fact.Outs[i-paramOffset(callee)]
+	return ok && out.Clean.IsWrapping()
+}
+
+// outFacts describes pointer parameters the function writes errors with stacktraces through, as exported in facts.
+func (e *SSAEngine) outFacts(fn *ssa.Function) map[int]model.OutParam {
+	var outs map[int]model.OutParam
+	for _, i := range outParams(fn.Signature) {
+		if !e.addressCarriesStack(fn.Params[i+paramOffset(fn)], nil, map[ssa.Value]bool{}) {
+			continue
+		}
+		if outs == nil {
+			outs = make(map[int]model.OutParam)
+		}
+		outs[i] = model.OutParam{Clean: model.Wrapping, Always: model.Wrapping}
+	}
+	return outs
+}
@@ -1,17 +1,20 @@
 package errstack
 
 import (
+	"cmp"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"maps"
 	"reflect"
+	"slices"
+	"strconv"
 
 	"github.com/AdamBrianBright/errstack/internal/config"
 	"github.com/AdamBrianBright/errstack/internal/helpers"
 	"github.com/AdamBrianBright/errstack/internal/log"
 	"github.com/AdamBrianBright/errstack/internal/model"
-	"github.com/AdamBrianBright/errstack/internal/passes/preload_packages"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/ctrlflow"
@@ -35,6 +38,14 @@ Algorithm:
 Configuration:
 - wrapperFunctions: Functions that add stacktraces (e.g., errors.Wrap)
 - cleanFunctions: Functions that don't add stacktraces (e.g., errors.New)
+- resetFunctions: Functions that drop stacktraces of the errors they are given
+- unwrapFunctions: Functions unwrapping errors (e.g., errors.Cause, errors.As)
+- unwrapPolicy: Whether unwrapped errors keep stacktraces: "may" (default), "keep" or "clean"
+- stackTypes: Error types whose values carry stacktraces
+- inferWrappers: Treat functions capturing stacktraces with runtime.Callers and alike as wrappers
+- engine: Analysis engine, "ast" (default) or "ssa"
+- unknownCallPolicy: What to assume about errors of unknown callees: "clean" (default), "wrapping" or "report"
+- severity: Categories of diagnostics for errors that always or sometimes have stacktraces, or of unknown origin
 
 The analyzer supports suggested fixes to replace unnecessary wrapping 
 with simpler alternatives like errors.WithMessage.
@@ -45,20 +56,44 @@ var Analyzer = &analysis.Analyzer{
 	Doc:        _doc,
 	Run:        helpers.WrapRun(run),
 	ResultType: reflect.TypeOf((*helpers.Result[*Result])(nil)),
-	Requires:   []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer, config.Analyzer, preload_packages.Analyzer},
+	Requires:   []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer, config.Analyzer},
+	FactTypes:  []analysis.Fact{(*WrappingFact)(nil)},
 }
 
 func run(pass *analysis.Pass) (*Result, error) {
 	log.Log("Run\n")
-	loader, _ := helpers.GetResult[*preload_packages.Result](pass, preload_packages.Analyzer)
-	conf, _ := helpers.GetResult[*config.Config](pass, config.Analyzer)
+	conf, err := helpers.GetResult[*config.Config](pass, config.Analyzer)
 	defer log.Sync()
+	if err != nil {
+		return nil, err
+	}
 
 	var result = &Result{
 		OriginalFunctions:   []*model.Function{},
-		FunctionsWithErrors: map[token.Position]*model.Function{},
+		FunctionsWithErrors: map[model.Key]*model.Function{},
+		funcValues:          map[types.Object][]FuncValue{},
+		indexedInfos:        map[*types.Info]bool{},
+		probed:              map[probeKey][]model.Verdict{},
+		closures:            map[ast.Node]*localClosures{},
+		running:             map[*ast.FuncLit]bool{},
+		effects:             map[*ast.FuncLit][]closureEffect{},
+		globals:             map[*types.Var]model.Verdict{},
+		captures:            map[ast.Node]bool{},
 		conf:                conf,
-		loader:              loader,
+		pass:                pass,
+	}
+
+	if conf.IsExcluded(conf.PkgPath(pass.Pkg)) {
+		log.Log("Package %s is excluded\n", pass.Pkg.Path())
+		return result, nil
+	}
+
+	if conf.Engine == config.EngineSSA {
+		log.Log("RunSSA\n")
+		if err := result.RunSSA(pass); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
 	log.Log("FindFunctionsWithErrors\n")
@@ -67,11 +102,13 @@ func run(pass *analysis.Pass) (*Result, error) {
 	result.MarkTaintedFunctions()
 	log.Log("AnalyzeOriginalFunctions\n")
 	result.AnalyzeOriginalFunctions(pass)
+	log.Log("ExportFacts\n")
+	result.ExportFacts(pass)
 
 	for _, fn := range result.FunctionsWithErrors {
-		log.Log("Found function %s(%t): %s\n", fn.Name, fn.IsWrapping, fn.Pos.String())
+		log.Log("Found function %s(%s): %s\n", fn.Name, fn.Verdict, fn.Pos.String())
 		for _, callee := range fn.CalledBy {
-			log.Log("Function is called by %s(%t): %s\n", callee.Name, callee.IsWrapping, callee.Pos.String())
+			log.Log("Function is called by %s(%s): %s\n", callee.Name, callee.Verdict, callee.Pos.String())
 		}
 		log.Log("\n")
 	}
@@ -106,44 +143,89 @@ func (res *Result) FindFunctionsWithErrors(pass *analysis.Pass) {
 		}
 	})
 
-	visited := make(map[*model.Function]bool)
-	stack := make(model.Stack[*FunctionWithDepth], 0, 64)
-
-	// Initialize stack with original functions at depth 0
+	// Functions are discovered breadth-first, so depth is the length of the shortest call chain
+	// from the analyzed package and the optional MaxDepth budget cuts off the farthest functions first.
+	depths := make(map[*model.Function]int, len(res.OriginalFunctions))
+	queue := make([]*FunctionWithDepth, 0, len(res.OriginalFunctions))
 	for _, fn := range res.OriginalFunctions {
-		stack.Push(&FunctionWithDepth{Function: fn, Depth: 0})
+		if _, ok := depths[fn]; !ok {
+			depths[fn] = 0
+			queue = append(queue, &FunctionWithDepth{Function: fn, Depth: 0})
+		}
+	}
+	enqueue := func(fn *model.Function, depth int) {
+		if _, ok := depths[fn]; ok {
+			return
+		}
+		depths[fn] = depth
+		queue = append(queue, &FunctionWithDepth{Function: fn, Depth: depth})
 	}
 
-	for item := stack.Pop(); item != nil; item = stack.Pop() {
-		fnWithDepth := *item
-		function := fnWithDepth.Function
-		currentDepth := fnWithDepth.Depth
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		function := item.Function
+		currentDepth := item.Depth
 
 		log.Log("Populating %s (depth %d): %s\n", function.Name, currentDepth, function.Pos.String())
-		if visited[function] {
-			continue
-		}
-		visited[function] = true
 
-		// Check MaxDepth limit (ignore if MaxDepth <= 0)
+		// Check MaxDepth budget (ignore if MaxDepth <= 0)
 		if res.conf.MaxDepth > 0 && currentDepth >= res.conf.MaxDepth {
 			log.Log("Reached max depth %d for function %s, stopping traversal\n", res.conf.MaxDepth, function.Name)
 			continue
 		}
 
+		for _, target := range function.Targets {
+			enqueue(target, currentDepth+1)
+		}
+		if function.Body == nil {
+			// Virtual functions have no body to look for calls in
+			continue
+		}
 		ast.Inspect(function.Node, func(n ast.Node) bool {
 			if n == nil {
 				return false
 			}
-			_, ok := n.(*ast.CallExpr)
+			if lit, isLit := n.(*ast.FuncLit); isLit && n != function.Node {
+				// Closures may return their errors through the function creating them,
+				// e.g. when run by error groups, so they are analyzed before it
+				if fn := res.TryAddFunction(function.Info, cfgs, lit); fn != nil {
+					fn.CalledBy.AddUnique(function)
+					enqueue(fn, currentDepth+1)
+				}
+				return true
+			}
+			if rng, isRange := n.(*ast.RangeStmt); isRange && yieldSignature(function.Info.Types.TypeOf(rng.X)) != nil {
+				// Loops ranging over iterators get the errors the iterators yield
+				if _, isCall := ast.Unparen(rng.X).(*ast.CallExpr); !isCall {
+					if fn := res.resolveFuncValue(function.Info, cfgs, rng.X); fn != nil {
+						fn.CalledBy.AddUnique(function)
+						enqueue(fn, currentDepth+1)
+					}
+				}
+				return true
+			}
+			if lit, isLit := n.(*ast.CompositeLit); isLit && res.stackLiterals(function.Info)[lit] {
+				// Errors carrying stacktraces are created in place
+				function.Verdict = function.Verdict.Max(model.Wrapping)
+				return true
+			}
+			call, ok := n.(*ast.CallExpr)
 			if !ok {
 				return true
 			}
 			fn := res.TryAddCallExpr(function.Info, cfgs, n)
 			if fn != nil {
 				fn.CalledBy.AddUnique(function)
-				// Push with incremented depth
-				stack.Push(&FunctionWithDepth{Function: fn, Depth: currentDepth + 1})
+				enqueue(fn, currentDepth+1)
+				if res.isReset(fn) {
+					// Stacktraces of errors given to reset functions are dropped
+					return false
+				}
+			} else if returnsError(function.Info, call) {
+				// The callee returns errors, but there is nothing known about them
+				log.Log("Unresolved call %s in %s\n", function.Info.FormatNode(call.Fun), function.Name)
+				function.Verdict = function.Verdict.Max(res.unknownVerdict())
 			}
 			return true
 		})
@@ -151,85 +233,361 @@ func (res *Result) FindFunctionsWithErrors(pass *analysis.Pass) {
 }
 
 // MarkTaintedFunctions marks functions that return wrapped errors.
+// Wrapper functions are marked first, then the taint is propagated to their callers
+// by solving the condensed call graph.
 func (res *Result) MarkTaintedFunctions() {
 	matchClean := res.conf.CleanFunctions.Match
-	matchWrapper := res.conf.WrapperFunctions.Match
 
 	for _, function := range res.FunctionsWithErrors {
-		if matchClean(function.Pkg, function.Name) {
-			log.Log("Function %s.%s is clean, marking with '%t': %s\n", function.Pkg, function.Name, false, function.Pos.String())
-			function.IsWrapping = false
+		if matchClean(function.Pkg, function.Name) || res.isReset(function) || res.isUnwrap(function) {
+			log.Log("Function %s.%s is clean, marking with '%s': %s\n", function.Pkg, function.Name, model.Clean, function.Pos.String())
+			function.Verdict = model.Clean
 			continue
 		}
-		if matchWrapper(function.Pkg, function.Name) {
-			log.Log("Function %s.%s is taint, marking with '%t': %s\n", function.Pkg, function.Name, true, function.Pos.String())
-			function.IsWrapping = true
+		if res.isWrapper(function) {
+			log.Log("Function %s.%s is taint, marking with '%s': %s\n", function.Pkg, function.Name, model.AlwaysWrapping, function.Pos.String())
+			function.Verdict = model.AlwaysWrapping
 			continue
 		}
 	}
-	var visited = make(map[*model.Function]bool)
+
+	functions := make(map[*model.Function]bool, len(res.FunctionsWithErrors))
 	for _, function := range res.FunctionsWithErrors {
-		res.propagateWrapping(visited, function)
+		functions[function] = true
 	}
+	res.callGraph = NewCallGraph(functions)
+	res.callGraph.Solve(func(fn *model.Function) bool {
+		return matchClean(fn.Pkg, fn.Name) || res.isReset(fn) || res.isUnwrap(fn)
+	})
 }
 
-// propagateWrapping propagates wrapping information from the given function to all its callers.
-func (res *Result) propagateWrapping(visited map[*model.Function]bool, function *model.Function) {
-	log.Log("Propagating function %s.%s: %s\n", function.Pkg, function.Name, function.Pos.String())
-	if !function.IsWrapping || res.conf.CleanFunctions.Match(function.Pkg, function.Name) {
-		log.Log("Function %s.%s is not wrapping, skipping function\n", function.Pkg, function.Name)
+// AnalyzeOriginalFunctions walks over originally found functions CFG and reports if unnecessary wrapping is used.
+// Functions are analyzed callees first, so callers see which callees return stacktraces on all paths.
+func (res *Result) AnalyzeOriginalFunctions(pass *analysis.Pass) {
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+
+	originals := make(map[*model.Function]bool, len(res.OriginalFunctions))
+	for _, v := range res.OriginalFunctions {
+		originals[v] = true
+	}
+	for _, component := range res.callGraph.Components {
+		for _, v := range component {
+			if v.Body == nil && len(v.Targets) > 0 {
+				res.joinTargetResults(v)
+				continue
+			}
+			if !originals[v] || (v.Verdict == model.Clean && !hasParamSummaries(v)) {
+				continue
+			}
+			res.analyzeOriginalFunction(pass, cfgs, v)
+		}
+	}
+}
+
+// analyzeOriginalFunction runs a forward dataflow analysis over the CFG of the original function.
+// Each block gets its own input state joined from the output states of its predecessors, so
+// assignments in one branch never leak into sibling branches. Once the states are stable,
+// every block is walked once more to report unnecessary wrapping.
+func (res *Result) analyzeOriginalFunction(pass *analysis.Pass, cfgs *ctrlflow.CFGs, fn *model.Function) {
+	if fn.Block == nil {
+		return
+	}
+	sig := signatureOf(fn)
+	if sig == nil {
+		return
+	}
+	// Effects of closures depend on summaries of functions analyzed before
+	clear(res.effects)
+	walk := res.walkFunction(pass, cfgs, fn, sig)
+	res.reportWrapped(pass, cfgs, fn.Info, walk)
+	if res.isReset(fn) {
+		// Errors of reset functions never carry stacktraces, whatever the function does
 		return
 	}
 
-	// Use depth-aware traversal
-	stack := make(model.Stack[*FunctionWithDepth], 0, len(function.CalledBy))
-	for _, caller := range function.CalledBy {
-		stack.Push(&FunctionWithDepth{Function: caller, Depth: 0})
+	returns := walk.returns
+	fn.Results = returns.results
+	fn.Fields = returns.fields
+	fn.Callbacks = res.analyzeCallbacks(pass, cfgs, fn, sig)
+	fn.Outs = res.analyzeOutParams(pass, cfgs, fn, sig)
+	if walk.yields != nil {
+		fn.Yields = walk.yields.results
+	}
+	log.Log("Function %s returns %v\n", fn.Name, fn.Results)
+	if verdict, ok := returns.verdict(); ok && verdict == model.AlwaysWrapping && fn.Verdict != model.AlwaysWrapping {
+		log.Log("Function %s returns stacktraces on all paths\n", fn.Name)
+		fn.Verdict = model.AlwaysWrapping
 	}
+	fn.Wraps = res.wrappedParams(cfgs, fn, sig)
+}
 
-	for v := stack.Pop(); v != nil; v = stack.Pop() {
-		fnWithDepth := *v
-		fn := fnWithDepth.Function
-		currentDepth := fnWithDepth.Depth
+// walkFunction solves the dataflow of the function and walks every block once more
+// with the stable states, collecting returned errors and wrapped errors.
+func (res *Result) walkFunction(pass *analysis.Pass, cfgs *ctrlflow.CFGs, fn *model.Function, sig *types.Signature) *functionWalk {
+	blocks, in, _ := res.solveBlocks(pass, cfgs, fn, fn.Block, map[model.Place]model.Verdict{})
 
-		if visited[fn] {
-			continue
+	walk := &functionWalk{
+		returns:  newReturnSummary(sig.Results().Len()),
+		defers:   deferredClosures(fn.Body),
+		closures: nestedClosures(fn.Body),
+		wrapped:  map[*ast.CallExpr]*wrappedError{},
+	}
+	walk.yields, walk.yield = iteratorSummary(sig)
+	for _, block := range blocks {
+		res.analyzeOriginalFunctionBlock(pass, cfgs, fn, block, maps.Clone(in[block]), walk)
+	}
+	return walk
+}
+
+// hasParamSummaries reports whether the function declaration or literal has parameters holding functions
+// returning errors or pointing to errors, which are summarized even if the function returns no stacktraces.
+func hasParamSummaries(fn *model.Function) bool {
+	sig := signatureOf(fn)
+	return sig != nil && (len(callbackParams(sig)) > 0 || len(outParams(sig)) > 0)
+}
+
+// solveBlocks runs the forward dataflow analysis over blocks reachable from the entry block,
+// starting with the given state. Returns the blocks along with their stable input and output states.
+func (res *Result) solveBlocks(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
+	entry *cfg.Block,
+	initial map[model.Place]model.Verdict,
+) ([]*cfg.Block, map[*cfg.Block]map[model.Place]model.Verdict, map[*cfg.Block]map[model.Place]model.Verdict) {
+	blocks, preds := collectBlocks(entry)
+
+	in := make(map[*cfg.Block]map[model.Place]model.Verdict, len(blocks))
+	out := make(map[*cfg.Block]map[model.Place]model.Verdict, len(blocks))
+	worklist := make(model.Stack[*cfg.Block], 0, len(blocks))
+	for i := len(blocks) - 1; i >= 0; i-- {
+		worklist.Push(blocks[i])
+	}
+
+	for item := worklist.Pop(); item != nil; item = worklist.Pop() {
+		block := *item
+		state := maps.Clone(initial)
+		if block != entry {
+			state = joinVariables(preds[block], out)
 		}
-		visited[fn] = true
-		if res.conf.CleanFunctions.Match(fn.Pkg, fn.Name) {
+		in[block] = state
+
+		variables := maps.Clone(state)
+		res.analyzeOriginalFunctionBlock(pass, cfgs, fn, block, variables, nil)
+		if prev, ok := out[block]; ok && maps.Equal(prev, variables) {
 			continue
 		}
+		out[block] = variables
+		for _, succ := range block.Succs {
+			worklist.Push(succ)
+		}
+	}
+	return blocks, in, out
+}
 
-		// Check MaxDepth limit (ignore if MaxDepth <= 0)
-		if res.conf.MaxDepth > 0 && currentDepth >= res.conf.MaxDepth {
-			log.Log("Reached max depth %d for propagation to function %s.%s, stopping\n", res.conf.MaxDepth, fn.Pkg, fn.Name)
-			continue
+// functionWalk collects findings of the final walk over a function. Wrapped errors are reported
+// once the whole function is walked, since deferred closures are walked at every return statement.
+type functionWalk struct {
+	returns  *returnSummary                  // Errors returned by the function, nil inside deferred closures
+	defers   []*ast.FuncLit                  // Closures deferred by the function
+	closures []*ast.FuncLit                  // Outermost closures created by the function
+	wrapped  map[*ast.CallExpr]*wrappedError // Errors wrapped by wrapper calls, joined over all walks
+	yields   *returnSummary                  // Errors yielded by the iterator the function is or returns, nil if none
+	yield    *types.Var                      // Yield parameter of the iterator the function is
+}
+
+type wrappedError struct {
+	wrapper *model.Function
+	verdict model.Verdict
+}
+
+func (w *functionWalk) wrap(wrapper *model.Function, call *ast.CallExpr, verdict model.Verdict) {
+	if prev, ok := w.wrapped[call]; ok {
+		prev.verdict = prev.verdict.Join(verdict)
+		return
+	}
+	w.wrapped[call] = &wrappedError{wrapper: wrapper, verdict: verdict}
+}
+
+// reportWrapped reports wrapper calls wrapping errors that carry stacktraces in the order of their positions.
+func (res *Result) reportWrapped(pass *analysis.Pass, cfgs *ctrlflow.CFGs, info *model.Info, walk *functionWalk) {
+	calls := slices.SortedFunc(maps.Keys(walk.wrapped), func(a, b *ast.CallExpr) int {
+		return cmp.Compare(a.Pos(), b.Pos())
+	})
+	for _, call := range calls {
+		wrapped := walk.wrapped[call]
+		switch wrapped.verdict {
+		case model.Wrapping, model.AlwaysWrapping:
+			res.reportUnnecessaryWrapping(pass, cfgs, info, wrapped.wrapper, call, wrapped.verdict)
+		case model.Unknown:
+			res.reportUnknownWrapping(pass, info, wrapped.wrapper, call)
+		}
+	}
+}
+
+// joinTargetResults combines verdicts of results and yielded values of all functions the virtual function
+// may dispatch to. They stay unknown unless all targets have them analyzed separately.
+func (res *Result) joinTargetResults(fn *model.Function) {
+	fn.Results = res.joinTargetVerdicts(fn, func(target *model.Function) []model.Verdict { return target.Results })
+	fn.Yields = res.joinTargetVerdicts(fn, func(target *model.Function) []model.Verdict { return target.Yields })
+}
+
+func (res *Result) joinTargetVerdicts(fn *model.Function, get func(*model.Function) []model.Verdict) []model.Verdict {
+	var joined []model.Verdict
+	for _, target := range fn.Targets {
+		verdicts := get(target)
+		if verdicts == nil || (joined != nil && len(verdicts) != len(joined)) {
+			return nil
+		}
+		if joined == nil {
+			joined = make([]model.Verdict, len(verdicts))
+		}
+		for i, verdict := range verdicts {
+			joined[i] = joined[i].Max(verdict.May())
+		}
+	}
+	if fn.Partial {
+		// Functions missing from targets are unknown callees
+		for i := range joined {
+			joined[i] = joined[i].Max(res.unknownVerdict())
+		}
+	}
+	return joined
+}
+
+// signatureOf returns the signature of the function declaration or literal.
+func signatureOf(fn *model.Function) *types.Signature {
+	var typ types.Type
+	switch node := fn.Node.(type) {
+	case *ast.FuncDecl:
+		if obj := fn.Info.Types.Defs[node.Name]; obj != nil {
+			typ = obj.Type()
 		}
+	case *ast.FuncLit:
+		typ = fn.Info.Types.TypeOf(node)
+	}
+	sig, _ := typ.(*types.Signature)
+	return sig
+}
+
+// returnSummary joins verdicts of errors returned by all return statements of a function, per result.
+// Results never returning errors are clean.
+type returnSummary struct {
+	results []model.Verdict
+	found   []bool
+	fields  map[string]model.Verdict
+}
 
-		log.Log("Taint function %s.%s (depth %d): %s\n", fn.Pkg, fn.Name, currentDepth, fn.Pos.String())
-		fn.IsWrapping = true
+func newReturnSummary(results int) *returnSummary {
+	return &returnSummary{
+		results: make([]model.Verdict, results),
+		found:   make([]bool, results),
+	}
+}
 
-		// Push callers with incremented depth
-		for _, caller := range fn.CalledBy {
-			stack.Push(&FunctionWithDepth{Function: caller, Depth: currentDepth + 1})
+func (r *returnSummary) add(i int, verdict model.Verdict) {
+	if i >= len(r.results) {
+		return
+	}
+	if !r.found[i] {
+		r.results[i], r.found[i] = verdict, true
+		return
+	}
+	r.results[i] = r.results[i].Join(verdict)
+}
+
+// addFields joins verdicts of errors returned in fields and elements of results.
+func (r *returnSummary) addFields(fields map[string]model.Verdict) {
+	for path, verdict := range fields {
+		if r.fields == nil {
+			r.fields = make(map[string]model.Verdict)
+		}
+		if prev, ok := r.fields[path]; ok {
+			verdict = prev.Join(verdict)
 		}
+		r.fields[path] = verdict
 	}
 }
 
-// AnalyzeOriginalFunctions walks over originally found functions CFG and reports if unnecessary wrapping is used.
-func (res *Result) AnalyzeOriginalFunctions(pass *analysis.Pass) {
-	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+// verdict joins verdicts of all returned errors, reports false if no errors are returned.
+func (r *returnSummary) verdict() (model.Verdict, bool) {
+	var verdict model.Verdict
+	var found bool
+	for i, result := range r.results {
+		if !r.found[i] {
+			continue
+		}
+		if found {
+			verdict = verdict.Join(result)
+		} else {
+			verdict, found = result, true
+		}
+	}
+	return verdict, found
+}
 
-	var visited = make(map[*cfg.Block]bool)
-	var variables = make(map[token.Position]bool)
-	for _, v := range res.OriginalFunctions {
-		if !v.IsWrapping {
+// collectBlocks returns all blocks reachable from the entry block in depth-first order
+// along with the predecessors of each block.
+func collectBlocks(entry *cfg.Block) ([]*cfg.Block, map[*cfg.Block][]*cfg.Block) {
+	var blocks []*cfg.Block
+	preds := make(map[*cfg.Block][]*cfg.Block)
+	visited := make(map[*cfg.Block]bool)
+	stack := model.Stack[*cfg.Block]{entry}
+	for item := stack.Pop(); item != nil; item = stack.Pop() {
+		block := *item
+		if visited[block] {
 			continue
 		}
-		clear(visited)
-		clear(variables)
-		res.analyzeOriginalFunctionBlock(pass, cfgs, v.Block, visited, variables, 0)
+		visited[block] = true
+		blocks = append(blocks, block)
+		for i := len(block.Succs) - 1; i >= 0; i-- {
+			succ := block.Succs[i]
+			preds[succ] = append(preds[succ], block)
+			stack.Push(succ)
+		}
 	}
+	return blocks, preds
+}
+
+// joinVariables merges output states of the given blocks. A variable carries a stack
+// after the merge if it carries one on any of the incoming paths, and always carries
+// one only if it does on all of them. Blocks not visited yet are skipped and places
+// missing from a state are nil there, so they are clean on that path.
+func joinVariables(blocks []*cfg.Block, states map[*cfg.Block]map[model.Place]model.Verdict) map[model.Place]model.Verdict {
+	incoming := make([]map[model.Place]model.Verdict, 0, len(blocks))
+	for _, block := range blocks {
+		if state, ok := states[block]; ok {
+			incoming = append(incoming, state)
+		}
+	}
+	return joinStates(incoming...)
+}
+
+// joinStates merges states of all incoming paths, see joinVariables.
+func joinStates(states ...map[model.Place]model.Verdict) map[model.Place]model.Verdict {
+	var joined map[model.Place]model.Verdict
+	for _, state := range states {
+		if joined == nil {
+			joined = maps.Clone(state)
+			continue
+		}
+		for place, verdict := range joined {
+			if _, found := state[place]; !found {
+				joined[place] = verdict.Join(model.Clean)
+			}
+		}
+		for place, verdict := range state {
+			prev, found := joined[place]
+			if !found {
+				prev = model.Clean
+			}
+			joined[place] = prev.Join(verdict)
+		}
+	}
+	if joined == nil {
+		joined = make(map[model.Place]model.Verdict)
+	}
+	return joined
 }
 
 type StackCall struct {
@@ -242,32 +600,29 @@ type FunctionWithDepth struct {
 	Depth    int
 }
 
-// analyzeOriginalFunctionBlock walks over the CFG of the original function and
+// analyzeOriginalFunctionBlock walks over a single block of the original function CFG,
 // traces all error variables and finds errors that are unnecessarily wrapped.
+// The variables state is updated in place. Wrapped and returned errors are only collected
+// during the final walk. Deferred closures are skipped, they run at return statements.
 func (res *Result) analyzeOriginalFunctionBlock(
 	pass *analysis.Pass,
 	cfgs *ctrlflow.CFGs,
+	fn *model.Function,
 	block *cfg.Block,
-	visited map[*cfg.Block]bool,
-	variables map[token.Position]bool,
-	depth int,
+	variables map[model.Place]model.Verdict,
+	walk *functionWalk,
 ) {
-	if block == nil || visited[block] {
-		return
-	}
-
-	// Check MaxDepth limit (ignore if MaxDepth <= 0)
-	if res.conf.MaxDepth > 0 && depth >= res.conf.MaxDepth {
-		log.Log("Reached max depth %d in CFG traversal, stopping\n", res.conf.MaxDepth)
+	if block == nil {
 		return
 	}
 	info := model.NewInfo(pass)
-	matchWrapping := res.conf.WrapperFunctions.Match
-	replaceWith := res.conf.WrapperFunctions.ReplaceWith
-	replaceWithFunction := res.conf.WrapperFunctions.ReplaceWithFunction
+	closures := res.closuresOf(info, fn)
 
-	visited[block] = true
 	log.Log("Visiting block %v\n", block)
+	if rng, ok := block.Stmt.(*ast.RangeStmt); ok && block.Kind == cfg.KindRangeBody {
+		res.assignRangeValue(pass, cfgs, info, rng, variables)
+		res.assignYielded(cfgs, info, rng, variables)
+	}
 
 	for _, item := range block.Nodes {
 		ast.Inspect(item, func(n ast.Node) bool {
@@ -276,94 +631,93 @@ func (res *Result) analyzeOriginalFunctionBlock(
 			}
 			log.Log("Visiting node %s\n", info.FormatNode(n))
 			switch node := n.(type) {
+			case *ast.DeferStmt:
+				return deferredClosure(node) == nil
+			case *ast.FuncLit:
+				// Closures are walked where they run
+				return false
 			case *ast.CallExpr:
-				fn := res.TryAddCallExpr(info, cfgs, node)
-				if fn == nil || !matchWrapping(fn.Pkg, fn.Name) {
+				wrapper := res.TryAddCallExpr(info, cfgs, node)
+				args := res.wrappedArgs(wrapper, node)
+				if len(args) == 0 {
 					return true
 				}
-				var wrapping bool
-				for _, arg := range node.Args {
+				verdict := model.Clean
+				for _, arg := range args {
 					result := res.analyzeCallStack(pass, cfgs, info, arg, variables)
 					if result != nil {
-						wrapping = wrapping || *result
+						verdict = verdict.Max(*result)
 					}
 				}
-				if wrapping {
-					fn.IsWrapping = true
-					log.Log("Node unnecessarily wraps error with stacktrace %s\n", info.FormatNode(node))
-					errorArgument := res.getErrorArgument(cfgs, info, node)
-					var fixes []analysis.SuggestedFix
-					if errorArgument != nil {
-						if len(node.Args) == 1 {
-							fixes = []analysis.SuggestedFix{
-								{
-									Message: "Remove unnecessary error wrapping",
-									TextEdits: []analysis.TextEdit{
-										{
-											Pos:     node.Pos(),
-											End:     node.End(),
-											NewText: []byte(info.FormatNode(errorArgument)),
-										},
-									},
-								},
-							}
-						} else {
-							message := "Replace unnecessary error wrapping"
-							newText := info.FormatNode(node)
-							if len(node.Args) == 2 {
-								newText = replaceWith(fn.Pkg, fn.Name, newText)
-							} else {
-								newText = replaceWithFunction(fn.Pkg, fn.Name, newText)
-							}
-							if newText != "" {
-								fixes = []analysis.SuggestedFix{
-									{
-										Message: message,
-										TextEdits: []analysis.TextEdit{
-											{
-												Pos:     node.Pos(),
-												End:     node.End(),
-												NewText: []byte(newText),
-											},
-										},
-									},
-								}
-							}
-						}
-					}
-					pass.Report(analysis.Diagnostic{
-						Pos:            node.Pos(),
-						End:            node.End(),
-						Category:       "",
-						Message:        fmt.Sprintf("%s call unnecessarily wraps error with stacktrace. Replace with errors.WithMessage() or fmt.Errorf()", fn.Name),
-						URL:            "",
-						SuggestedFixes: fixes,
-						Related:        nil,
-					})
+				if walk != nil {
+					walk.wrap(wrapper, node, verdict)
+				}
+				return true
+			case *ast.ReturnStmt:
+				if walk != nil && walk.returns != nil && !walk.inClosure(node) {
+					res.collectReturns(pass, cfgs, info, fn, node, variables, walk)
 				}
 				return true
 			}
 			return true
 		})
 		// Propagate wrapping information to new assignments
+		ran := make(map[*ast.FuncLit]bool)
 		ast.Inspect(item, func(node ast.Node) bool {
 			if node == nil {
 				return false
 			}
+			switch stmt := node.(type) {
+			case *ast.DeferStmt:
+				return deferredClosure(stmt) == nil
+			case *ast.SendStmt:
+				res.sendValue(pass, cfgs, info, stmt, variables)
+				return true
+			case *ast.GoStmt:
+				// Goroutines may run at any point after they are started
+				lits := closures.calledClosures(info, stmt.Call)
+				res.runClosures(pass, cfgs, info, fn, lits, false, variables, walk)
+				for _, lit := range lits {
+					ran[lit] = true
+				}
+				return true
+			case *ast.FuncLit:
+				if !ran[stmt] && !closures.held[stmt] {
+					// Closures passed around may run at any point after they are created
+					res.runClosures(pass, cfgs, info, fn, []*ast.FuncLit{stmt}, false, variables, walk)
+				}
+				return false
+			case *ast.CallExpr:
+				if lits := closures.calledClosures(info, stmt); len(lits) > 0 && !ran[lits[0]] {
+					res.runClosures(pass, cfgs, info, fn, lits, true, variables, walk)
+					for _, lit := range lits {
+						ran[lit] = true
+					}
+				}
+				res.runInGroup(info, cfgs, stmt, variables)
+				res.writeOutParams(info, cfgs, stmt, variables)
+				res.writeUnwrapTarget(pass, cfgs, info, stmt, variables)
+				if walk != nil && walk.yields != nil && !walk.inClosure(stmt) {
+					res.collectYield(pass, cfgs, info, stmt, variables, walk)
+				}
+				return true
+			}
+			if spec, isSpec := node.(*ast.ValueSpec); isSpec {
+				res.declareVariables(pass, cfgs, info, spec, variables)
+				return true
+			}
 			assignStmt, ok := node.(*ast.AssignStmt)
 			if !ok {
 				return true
 			}
-			lhs := make([]*token.Position, len(assignStmt.Lhs))
+			lhs := make([]*model.Place, len(assignStmt.Lhs))
 			found := false
 			for i, expr := range assignStmt.Lhs {
-				if id, idOk := expr.(*ast.Ident); idOk && id != nil {
-					obj := info.Types.ObjectOf(id)
-					if !isObjectError(obj) {
-						continue
-					}
-					objPos := info.Fset.Position(obj.Pos())
-					lhs[i] = &objPos
+				if !containsError(info.Types.TypeOf(expr)) {
+					continue
+				}
+				if place, isPlace := placeOf(info, expr); isPlace {
+					lhs[i] = &place
 					found = true
 				}
 			}
@@ -372,56 +726,268 @@ func (res *Result) analyzeOriginalFunctionBlock(
 			}
 			log.Log("AssignStmt %s\n", info.FormatNode(assignStmt))
 
-			if len(assignStmt.Rhs) == 1 {
-				log.Log("AssignStmt Rhs[0] %s\n", info.FormatNode(assignStmt.Rhs[0]))
-				callStackWrapping := res.analyzeCallStack(pass, cfgs, info, assignStmt.Rhs[0], variables)
-				if callStackWrapping == nil {
-					log.Log("AssignStmt Rhs[0] is nil\n")
-					return true
-				}
-				log.Log("AssignStmt Rhs[0] is %t\n", *callStackWrapping)
-				for _, lh := range lhs {
-					if lh == nil {
-						continue
-					}
-					log.Log("Updating %s as %t\n", lh.String(), *callStackWrapping)
-					variables[*lh] = *callStackWrapping
+			if res.assignResults(pass, cfgs, info, lhs, assignStmt.Rhs, variables) {
+				return true
+			}
+			if len(assignStmt.Rhs) != len(assignStmt.Lhs) {
+				// Comma-ok expressions assign the value to the first place only
+				if lhs[0] != nil {
+					res.assignValue(pass, cfgs, info, *lhs[0], assignStmt.Rhs[0], variables)
 				}
-			} else {
-				log.Log("AssignStmt Rhs %d\n", len(assignStmt.Rhs))
-				for i, lh := range lhs {
-					if lh == nil {
-						continue
-					}
-					result := res.analyzeCallStack(pass, cfgs, info, assignStmt.Rhs[i], variables)
-					if result != nil {
-						log.Log("AssignStmt Rhs[%d] is %t\n", i, *result)
-						log.Log("Updating %s as %t\n", lh.String(), *result)
-						variables[*lh] = *result
-					} else {
-						log.Log("AssignStmt Rhs[%d] is nil\n", i)
-					}
+				return true
+			}
+			for i, place := range lhs {
+				if place != nil {
+					res.assignValue(pass, cfgs, info, *place, assignStmt.Rhs[i], variables)
 				}
 			}
 
 			return true
 		})
 	}
-	for _, branch := range block.Succs {
-		res.analyzeOriginalFunctionBlock(pass, cfgs, branch, visited, variables, depth+1)
+}
+
+// declareVariables records verdicts of error variables declared by the spec.
+// Variables declared without values are nil, so they are clean until assigned.
+func (res *Result) declareVariables(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	spec *ast.ValueSpec,
+	variables map[model.Place]model.Verdict,
+) {
+	lhs := make([]*model.Place, len(spec.Names))
+	for i, name := range spec.Names {
+		obj := info.Types.Defs[name]
+		if obj == nil || !containsError(obj.Type()) {
+			continue
+		}
+		place := model.VarPlace(obj)
+		clearPlaces(variables, place)
+		if isObjectError(obj) {
+			variables[place] = model.Clean
+		}
+		lhs[i] = &place
+	}
+	if res.assignResults(pass, cfgs, info, lhs, spec.Values, variables) {
+		return
+	}
+	for i, place := range lhs {
+		if place == nil {
+			continue
+		}
+		if len(spec.Values) == len(spec.Names) {
+			res.assignValue(pass, cfgs, info, *place, spec.Values[i], variables)
+		}
+		log.Log("Declaring %s as %s\n", spec.Names[i].Name, variables[*place])
+	}
+}
+
+// assignResults assigns results of a single call returning a tuple to the places result by result.
+// Reports false if the expressions are not such a call.
+func (res *Result) assignResults(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	lhs []*model.Place,
+	rhs []ast.Expr,
+	variables map[model.Place]model.Verdict,
+) bool {
+	results := res.analyzeCallResults(pass, cfgs, info, rhs, variables)
+	if results == nil {
+		return false
+	}
+	call := ast.Unparen(rhs[0]).(*ast.CallExpr)
+	tuple := info.Types.TypeOf(call).(*types.Tuple)
+	fn := res.TryAddCallExpr(info, cfgs, call)
+	for i, place := range lhs {
+		if place == nil || i >= len(results) {
+			continue
+		}
+		if isErrorType(tuple.At(i).Type()) {
+			storeValue(variables, *place, results[i])
+			continue
+		}
+		var parts map[string]model.Verdict
+		if fn != nil {
+			parts = resultParts(fn, i)
+		}
+		assignParts(variables, *place, parts)
+	}
+	return true
+}
+
+// collectReturns adds verdicts of errors returned by the return statement to the summary.
+// Nil errors are not collected, since they carry nothing to wrap.
+func (res *Result) collectReturns(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	fn *model.Function,
+	ret *ast.ReturnStmt,
+	variables map[model.Place]model.Verdict,
+	walk *functionWalk,
+) {
+	returns := walk.returns
+	if defers := walk.deferredAt(ret); len(defers) > 0 {
+		state, nils := res.runDefers(pass, cfgs, info, fn, ret, variables, defers, walk)
+		if len(namedResults(info, fn)) > 0 {
+			// Deferred closures may change named results after the returned values are assigned
+			res.collectNamedResults(info, fn, state, returns, nils)
+			return
+		}
+	}
+	if len(ret.Results) == 0 {
+		// Bare returns return named results
+		res.collectNamedResults(info, fn, variables, returns, nil)
+		return
+	}
+	if results := res.analyzeCallResults(pass, cfgs, info, ret.Results, variables); results != nil {
+		// Results of the called function are returned as they are
+		for i, verdict := range results {
+			returns.add(i, verdict)
+		}
+		if fn := res.TryAddCallExpr(info, cfgs, ast.Unparen(ret.Results[0]).(*ast.CallExpr)); fn != nil {
+			returns.addFields(fn.Fields)
+		}
+		return
+	}
+	fields := make(map[string]model.Verdict)
+	for i, result := range ret.Results {
+		typ := info.Types.TypeOf(result)
+		if !isErrorType(typ) {
+			res.collectIterator(cfgs, info, result, walk)
+			if containsError(typ) {
+				maps.Copy(fields, res.resultFields(pass, cfgs, info, i, result, variables))
+			}
+			continue
+		}
+		if verdict := res.analyzeCallStack(pass, cfgs, info, result, variables); verdict != nil {
+			returns.add(i, *verdict)
+		}
+	}
+	returns.addFields(fields)
+}
+
+// collectNamedResults adds verdicts of errors held by named results to the summary, except the skipped ones.
+func (res *Result) collectNamedResults(
+	info *model.Info,
+	fn *model.Function,
+	variables map[model.Place]model.Verdict,
+	returns *returnSummary,
+	skip map[int]bool,
+) {
+	fields := make(map[string]model.Verdict)
+	for i, obj := range namedResults(info, fn) {
+		if obj == nil || skip[i] {
+			continue
+		}
+		if verdict, ok := variables[model.VarPlace(obj)]; ok && isObjectError(obj) {
+			returns.add(i, verdict)
+		} else if !isObjectError(obj) && containsError(obj.Type()) {
+			for path, verdict := range subPlaces(variables, model.VarPlace(obj)) {
+				if path != "" {
+					fields[strconv.Itoa(i)+path] = verdict
+				}
+			}
+		}
+	}
+	returns.addFields(fields)
+}
+
+// reportUnknownWrapping reports the wrapper function call wrapping errors the analysis knows nothing about.
+func (res *Result) reportUnknownWrapping(pass *analysis.Pass, info *model.Info, fn *model.Function, node *ast.CallExpr) {
+	log.Log("Node wraps error of unknown origin %s\n", info.FormatNode(node))
+	pass.Report(analysis.Diagnostic{
+		Pos:            node.Pos(),
+		End:            node.End(),
+		Category:       res.conf.Severity.Unknown,
+		Message:        fmt.Sprintf("%s call wraps error of unknown origin, unable to tell whether it already has a stacktrace", fn.Name),
+		URL:            "",
+		SuggestedFixes: nil,
+		Related:        nil,
+	})
+}
+
+// reportUnnecessaryWrapping reports the wrapper function call and suggests a fix if possible.
+// Calls of wrappers and helpers the analyzer inferred itself get fixes only if they inline a configured wrapper,
+// since dropping them could lose their messages or side effects, otherwise the message tells how to get one.
+func (res *Result) reportUnnecessaryWrapping(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	fn *model.Function,
+	node *ast.CallExpr,
+	verdict model.Verdict,
+) {
+	replaceWith := res.conf.WrapperFunctions.ReplaceWith
+	replaceWithFunction := res.conf.WrapperFunctions.ReplaceWithFunction
+	inferred := !res.conf.WrapperFunctions.Match(fn.Pkg, fn.Name)
+
+	log.Log("Node unnecessarily wraps error with stacktrace %s\n", info.FormatNode(node))
+	errorArgument := res.getErrorArgument(cfgs, info, node)
+	var fixes []analysis.SuggestedFix
+	if errorArgument != nil {
+		message, newText := "Replace unnecessary error wrapping", info.FormatNode(node)
+		switch {
+		case inferred:
+			newText = res.helperReplacement(cfgs, info, node)
+		case len(node.Args) == 1:
+			message, newText = "Remove unnecessary error wrapping", info.FormatNode(errorArgument)
+		case len(node.Args) == 2:
+			newText = replaceWith(fn.Pkg, fn.Name, newText)
+		default:
+			newText = replaceWithFunction(fn.Pkg, fn.Name, newText)
+		}
+		if newText != "" {
+			fixes = []analysis.SuggestedFix{
+				{
+					Message: message,
+					TextEdits: []analysis.TextEdit{
+						{
+							Pos:     node.Pos(),
+							End:     node.End(),
+							NewText: []byte(newText),
+						},
+					},
+				},
+			}
+		}
+	}
+	category, certainty := res.conf.Severity.Sometimes, "sometimes already has"
+	if verdict == model.AlwaysWrapping {
+		category, certainty = res.conf.Severity.Always, "always has"
 	}
+	message := fmt.Sprintf(
+		"%s call unnecessarily wraps error with stacktrace. Replace with errors.WithMessage() or fmt.Errorf(). The error %s a stacktrace",
+		fn.Name,
+		certainty,
+	)
+	if inferred && len(fixes) == 0 {
+		message += fmt.Sprintf(". No fix is suggested for %s, list it in wrapperFunctions with replaceWith to get one", fn.Name)
+	}
+	pass.Report(analysis.Diagnostic{
+		Pos:            node.Pos(),
+		End:            node.End(),
+		Category:       category,
+		Message:        message,
+		URL:            "",
+		SuggestedFixes: fixes,
+		Related:        nil,
+	})
 }
 
-var trueValue = true
-var falseValue = false
+var cleanValue = model.Clean
 
+// analyzeCallStack returns the verdict of the error the expression evaluates to,
+// or nil if the expression is not an error.
 func (res *Result) analyzeCallStack(
 	pass *analysis.Pass,
 	cfgs *ctrlflow.CFGs,
 	info *model.Info,
 	n ast.Node,
-	variables map[token.Position]bool,
-) *bool {
+	variables map[model.Place]model.Verdict,
+) *model.Verdict {
 	if n == nil {
 		return nil
 	}
@@ -432,43 +998,92 @@ func (res *Result) analyzeCallStack(
 			return nil
 		}
 		log.Log("CallExpr %s\n", info.FormatNode(node.Fun))
+		if tv, ok := info.Types.Types[node.Fun]; ok && tv.IsType() && len(node.Args) == 1 {
+			// Conversions keep the error they convert
+			return res.analyzeCallStack(pass, cfgs, info, node.Args[0], variables)
+		}
+		if verdict := res.waitForGroup(info, node, variables); verdict != nil {
+			return verdict
+		}
+		if verdict, ok := res.probedCall(info, node); ok {
+			return &verdict
+		}
 		fn := res.TryAddCallExpr(info, cfgs, node)
 		if fn == nil {
-			// If we can't find the function, assume it returns a clean error (not wrapped)
-			// This handles standard library functions like json.Marshal properly
-			return &falseValue
+			if returnsError(info, node) {
+				// Neither source nor facts are available for the callee
+				unknown := res.unknownVerdict()
+				log.Log("CallExpr Function is unresolved, assuming %s\n", unknown)
+				return &unknown
+			}
+			// Functions not returning errors can't return wrapped errors
+			return &cleanValue
 		}
 		log.Log("CallExpr Function %s\n", fn.Name)
-		if fn.IsWrapping {
-			log.Log("CallExpr Function is wrapping\n")
-			return &trueValue
-		}
-		for i, arg := range node.Args {
-			log.Log("CallExpr Arg[%d] %s\n", i, info.FormatNode(arg))
-			result := res.analyzeCallStack(pass, cfgs, info, arg, variables)
-			if result != nil {
-				return result
-			}
+		if res.isReset(fn) {
+			log.Log("CallExpr Function resets stacktraces\n")
+			return &cleanValue
 		}
-		return &falseValue
+		if res.isUnwrap(fn) {
+			verdict := res.unwrapResult(pass, cfgs, info, node, variables)
+			log.Log("CallExpr Function unwraps %s\n", verdict)
+			return &verdict
+		}
+		if verdict, ok := res.callbackResult(pass, cfgs, info, fn, node, 0); ok {
+			log.Log("CallExpr Function returns errors of callbacks, %s\n", verdict)
+			return &verdict
+		}
+		if fn.Verdict.IsWrapping() {
+			log.Log("CallExpr Function is %s\n", fn.Verdict)
+			verdict := fn.Verdict
+			return &verdict
+		}
+		verdict := fn.Verdict
+		if result := res.analyzeArguments(pass, cfgs, info, node, variables); result != nil {
+			verdict = verdict.Max(*result)
+		}
+		return &verdict
 	case *ast.Ident:
 		log.Log("Ident %s\n", info.FormatNode(node))
 		if obj := info.Types.ObjectOf(node); obj != nil {
 			log.Log("Ident Object error\n")
 			if isObjectError(obj) {
-				log.Log("Ident Object is error\n")
-				if variables[info.Fset.Position(obj.Pos())] {
-					log.Log("Ident Object is error and variables[%t]\n", variables[info.Fset.Position(obj.Pos())])
-					return &trueValue
-				} else {
-					log.Log("Ident Object is error and variables[%t]\n", variables[info.Fset.Position(obj.Pos())])
-					return &falseValue
-				}
+				verdict := res.placeVerdict(pass, cfgs, info, model.VarPlace(obj), variables)
+				log.Log("Ident Object is error and variables[%s]\n", verdict)
+				return &verdict
 			}
 		}
 		return nil
+	case *ast.CompositeLit:
+		if !res.stackLiterals(info)[node] {
+			return nil
+		}
+		log.Log("CompositeLit carries a stacktrace\n")
+		verdict := model.AlwaysWrapping
+		return &verdict
+	case *ast.SelectorExpr, *ast.IndexExpr, *ast.UnaryExpr:
+		log.Log("Place %s\n", info.FormatNode(node))
+		if !isErrorType(info.Types.TypeOf(node.(ast.Expr))) {
+			return nil
+		}
+		if unary, ok := node.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			// Pointers to literals are errors of their own
+			return res.analyzeCallStack(pass, cfgs, info, ast.Unparen(unary.X), variables)
+		}
+		place, ok := placeOf(info, node.(ast.Expr))
+		if !ok {
+			return nil
+		}
+		verdict := res.placeVerdict(pass, cfgs, info, place, variables)
+		log.Log("Place is error and variables[%s]\n", verdict)
+		return &verdict
 	case *ast.StarExpr:
 		log.Log("StarExpr %s\n", info.FormatNode(node))
+		if place, ok := placeOf(info, node); ok && isErrorType(info.Types.TypeOf(node)) {
+			// Errors pointed to are held in the place of the pointer
+			verdict := res.placeVerdict(pass, cfgs, info, place, variables)
+			return &verdict
+		}
 		return res.analyzeCallStack(pass, cfgs, info, node.X, variables)
 	case *ast.ParenExpr:
 		log.Log("ParenExpr %s\n", info.FormatNode(node))
@@ -477,12 +1092,88 @@ func (res *Result) analyzeCallStack(
 	return nil
 }
 
+// analyzeCallResults returns verdicts of each result of the call if the expressions are a single call
+// returning a tuple, nil otherwise. Functions with results analyzed separately only taint the results
+// their stacktraces are returned through.
+func (res *Result) analyzeCallResults(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	exprs []ast.Expr,
+	variables map[model.Place]model.Verdict,
+) []model.Verdict {
+	if len(exprs) != 1 {
+		return nil
+	}
+	call, ok := ast.Unparen(exprs[0]).(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	tuple, ok := info.Types.TypeOf(call).(*types.Tuple)
+	if !ok {
+		return nil
+	}
+	probed, isProbed := res.probedCall(info, call)
+	fn := res.TryAddCallExpr(info, cfgs, call)
+	results := make([]model.Verdict, tuple.Len())
+	for i := range results {
+		if !isErrorType(tuple.At(i).Type()) {
+			continue
+		}
+		if isProbed {
+			results[i] = probed
+			continue
+		}
+		if fn == nil {
+			// Neither source nor facts are available for the callee
+			results[i] = res.unknownVerdict()
+			continue
+		}
+		if res.isReset(fn) {
+			results[i] = model.Clean
+			continue
+		}
+		if verdict, ok := res.callbackResult(pass, cfgs, info, fn, call, i); ok {
+			results[i] = verdict
+			continue
+		}
+		results[i] = fn.ResultVerdict(i)
+		if results[i].IsWrapping() {
+			continue
+		}
+		if result := res.analyzeArguments(pass, cfgs, info, call, variables); result != nil {
+			results[i] = results[i].Max(*result)
+		}
+	}
+	log.Log("CallExpr %s returns %v\n", info.FormatNode(call.Fun), results)
+	return results
+}
+
+// analyzeArguments returns the verdict of the first argument of the call the verdict is known of,
+// since functions not adding stacktraces may still return errors wrapping their arguments.
+func (res *Result) analyzeArguments(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	call *ast.CallExpr,
+	variables map[model.Place]model.Verdict,
+) *model.Verdict {
+	for i, arg := range call.Args {
+		log.Log("CallExpr Arg[%d] %s\n", i, info.FormatNode(arg))
+		if result := res.analyzeCallStack(pass, cfgs, info, arg, variables); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
 func (res *Result) getErrorArgument(cfgs *ctrlflow.CFGs, info *model.Info, call *ast.CallExpr) ast.Expr {
 	if len(call.Args) == 0 {
 		return nil
 	}
 	for _, rootArg := range call.Args {
 		untypedArg := rootArg
+	unwrap:
 		for {
 			switch arg := untypedArg.(type) {
 			case *ast.Ident:
@@ -490,11 +1181,13 @@ func (res *Result) getErrorArgument(cfgs *ctrlflow.CFGs, info *model.Info, call
 				if isObjectError(obj) {
 					return rootArg
 				}
+				break unwrap
 			case *ast.CallExpr:
 				fn := res.TryAddCallExpr(info, cfgs, arg)
 				if fn != nil {
 					return rootArg
 				}
+				break unwrap
 			case *ast.StarExpr:
 				untypedArg = arg.X
 			case *ast.ParenExpr:
@@ -503,8 +1196,10 @@ func (res *Result) getErrorArgument(cfgs *ctrlflow.CFGs, info *model.Info, call
 				untypedArg = arg.Sel
 			case *ast.IndexExpr:
 				untypedArg = arg.X
+			case *ast.IndexListExpr:
+				untypedArg = arg.X
 			default:
-				break
+				break unwrap
 			}
 		}
 	}
@@ -512,6 +1207,41 @@ func (res *Result) getErrorArgument(cfgs *ctrlflow.CFGs, info *model.Info, call
 	return nil
 }
 
+// returnsError reports whether the call returns an error among its results.
+// Conversions and builtins are not calls of functions returning errors.
+func returnsError(info *model.Info, call *ast.CallExpr) bool {
+	if tv, ok := info.Types.Types[call.Fun]; ok && (tv.IsType() || tv.IsBuiltin()) {
+		return false
+	}
+	switch typ := info.Types.TypeOf(call).(type) {
+	case *types.Tuple:
+		for i := 0; i < typ.Len(); i++ {
+			if isErrorType(typ.At(i).Type()) {
+				return true
+			}
+		}
+		return false
+	default:
+		return isErrorType(typ)
+	}
+}
+
+// isReset reports whether the function drops stacktraces of the errors it is given.
+func (res *Result) isReset(fn *model.Function) bool {
+	return res.conf.ResetFunctions.Match(fn.Pkg, fn.Name)
+}
+
+// unknownVerdict returns the verdict assumed for errors returned by unresolved callees.
+func (res *Result) unknownVerdict() model.Verdict {
+	switch res.conf.UnknownCallPolicy {
+	case config.UnknownCallWrapping:
+		return model.Wrapping
+	case config.UnknownCallReport:
+		return model.Unknown
+	}
+	return model.Clean
+}
+
 func isErrorType(typ types.Type) bool {
 	if typ == nil {
 		return false
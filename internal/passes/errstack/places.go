@@ -0,0 +1,366 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+)
+
+// placeOf returns the place the expression refers to: a variable, a field of it
+// or elements of it. Reports false if the expression is not addressable by a place.
+func placeOf(info *model.Info, expr ast.Expr) (model.Place, bool) {
+	switch node := expr.(type) {
+	case *ast.Ident:
+		obj, ok := info.Types.ObjectOf(node).(*types.Var)
+		if !ok {
+			return model.Place{}, false
+		}
+		return model.VarPlace(obj), true
+	case *ast.SelectorExpr:
+		sel, ok := info.Types.Selections[node]
+		if !ok {
+			// Package-level variables of other packages
+			return placeOf(info, node.Sel)
+		}
+		if sel.Kind() != types.FieldVal {
+			return model.Place{}, false
+		}
+		place, ok := placeOf(info, node.X)
+		if !ok {
+			return model.Place{}, false
+		}
+		return place.Field(node.Sel.Name), true
+	case *ast.IndexExpr:
+		if !isContainer(info.Types.TypeOf(node.X)) {
+			// Instantiations of generic functions
+			return model.Place{}, false
+		}
+		place, ok := placeOf(info, node.X)
+		if !ok {
+			return model.Place{}, false
+		}
+		return place.Elem(), true
+	case *ast.UnaryExpr:
+		if node.Op != token.ARROW {
+			return model.Place{}, false
+		}
+		// Values received from channels are their elements
+		place, ok := placeOf(info, node.X)
+		if !ok {
+			return model.Place{}, false
+		}
+		return place.Elem(), true
+	case *ast.StarExpr:
+		return placeOf(info, node.X)
+	case *ast.ParenExpr:
+		return placeOf(info, node.X)
+	}
+	return model.Place{}, false
+}
+
+// isContainer reports whether the type holds elements addressed by indexes or received from channels.
+func isContainer(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	switch typ.Underlying().(type) {
+	case *types.Slice, *types.Array, *types.Map, *types.Chan:
+		return true
+	}
+	return false
+}
+
+// containsError reports whether values of the type are errors or hold errors
+// in their fields or elements.
+func containsError(typ types.Type) bool {
+	return containsErrorType(typ, map[types.Type]bool{})
+}
+
+func containsErrorType(typ types.Type, visited map[types.Type]bool) bool {
+	if typ == nil || visited[typ] {
+		return false
+	}
+	visited[typ] = true
+	if isErrorType(typ) {
+		return true
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Pointer:
+		return containsErrorType(t.Elem(), visited)
+	case *types.Slice:
+		return containsErrorType(t.Elem(), visited)
+	case *types.Array:
+		return containsErrorType(t.Elem(), visited)
+	case *types.Map:
+		return containsErrorType(t.Elem(), visited)
+	case *types.Chan:
+		return containsErrorType(t.Elem(), visited)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if containsErrorType(t.Field(i).Type(), visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// assignValue stores verdicts of errors the expression holds into the place and its parts.
+// Places of elements are shared, so their previous verdicts are joined instead of overwritten.
+func (res *Result) assignValue(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	dst model.Place,
+	expr ast.Expr,
+	variables map[model.Place]model.Verdict,
+) {
+	if typ := info.Types.TypeOf(expr); isErrorType(typ) && !hasErrorFields(typ) {
+		if isNil(info, expr) {
+			// Nil errors carry nothing to wrap
+			storeValue(variables, dst, model.Clean)
+		} else if result := res.analyzeCallStack(pass, cfgs, info, expr, variables); result != nil {
+			storeValue(variables, dst, *result)
+		}
+		return
+	}
+	assignParts(variables, dst, res.valueParts(pass, cfgs, info, expr, variables))
+}
+
+// assignParts replaces verdicts of all parts of the place with the given ones.
+// Parts of elements are stored like other values assigned to elements.
+func assignParts(variables map[model.Place]model.Verdict, dst model.Place, parts map[string]model.Verdict) {
+	store := storeValue
+	if !dst.IsElem() {
+		clearPlaces(variables, dst)
+		store = storePlace
+	}
+	for path, verdict := range parts {
+		store(variables, model.Place{Object: dst.Object, Path: dst.Path + path}, verdict)
+	}
+}
+
+// hasErrorFields reports whether the type is a struct, or a pointer to one, holding errors in its fields,
+// e.g. a struct implementing error by embedding it.
+func hasErrorFields(typ types.Type) bool {
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	strct, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < strct.NumFields(); i++ {
+		if containsError(strct.Field(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueParts returns verdicts of errors held in the value and its parts, keyed by their paths relative to the value.
+// Nil errors and errors the analysis knows nothing about are left out.
+func (res *Result) valueParts(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	expr ast.Expr,
+	variables map[model.Place]model.Verdict,
+) map[string]model.Verdict {
+	parts := make(map[string]model.Verdict)
+	expr = ast.Unparen(expr)
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		// Pointers are not followed, &Result{} is the same as Result{}
+		expr = ast.Unparen(unary.X)
+	}
+	if typ := info.Types.TypeOf(expr); isErrorType(typ) {
+		if result := res.analyzeCallStack(pass, cfgs, info, expr, variables); result != nil {
+			parts[""] = *result
+		}
+		if !hasErrorFields(typ) {
+			return parts
+		}
+	}
+	switch value := expr.(type) {
+	case *ast.CompositeLit:
+		typ := info.Types.TypeOf(value)
+		if typ == nil {
+			return parts
+		}
+		strct, isStruct := typ.Underlying().(*types.Struct)
+		for i, elt := range value.Elts {
+			path := "[]"
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+				if key, isIdent := kv.Key.(*ast.Ident); isStruct && isIdent {
+					path = "." + key.Name
+				}
+			} else if isStruct && i < strct.NumFields() {
+				path = "." + strct.Field(i).Name()
+			}
+			addParts(parts, path, res.valueParts(pass, cfgs, info, elt, variables))
+		}
+	case *ast.CallExpr:
+		if id, ok := ast.Unparen(value.Fun).(*ast.Ident); ok && len(value.Args) > 0 {
+			if _, isBuiltin := info.Types.ObjectOf(id).(*types.Builtin); isBuiltin && id.Name == "append" {
+				// Appended slices keep the elements they had
+				addParts(parts, "", res.valueParts(pass, cfgs, info, value.Args[0], variables))
+				path := "[]"
+				if value.Ellipsis.IsValid() {
+					path = ""
+				}
+				for _, arg := range value.Args[1:] {
+					addParts(parts, path, res.valueParts(pass, cfgs, info, arg, variables))
+				}
+				return parts
+			}
+		}
+		if fn := res.TryAddCallExpr(info, cfgs, value); fn != nil {
+			addParts(parts, "", resultParts(fn, 0))
+		}
+	default:
+		if src, ok := placeOf(info, expr); ok {
+			addParts(parts, "", subPlaces(variables, src))
+		}
+	}
+	return parts
+}
+
+// addParts adds verdicts of parts of a value stored at the path, joining verdicts of elements sharing a place.
+func addParts(parts map[string]model.Verdict, path string, values map[string]model.Verdict) {
+	for part, verdict := range values {
+		part = path + part
+		if prev, ok := parts[part]; ok && strings.Contains(part, "[]") {
+			verdict = prev.Join(verdict)
+		}
+		parts[part] = verdict
+	}
+}
+
+// storePlace stores the verdict into the place, joining it with verdicts of other elements sharing the place.
+func storePlace(variables map[model.Place]model.Verdict, dst model.Place, verdict model.Verdict) {
+	if prev, ok := variables[dst]; ok && dst.IsElem() {
+		verdict = prev.Join(verdict)
+	}
+	log.Log("Updating %s%s as %s\n", placeName(dst), dst.Path, verdict)
+	variables[dst] = verdict
+}
+
+// storeValue stores the verdict of an assigned value into the place. Elements not assigned yet hold nil errors,
+// so the first value stored into the place shared by elements is joined with clean.
+func storeValue(variables map[model.Place]model.Verdict, dst model.Place, verdict model.Verdict) {
+	if _, ok := variables[dst]; !ok && dst.IsElem() {
+		verdict = model.Clean.Join(verdict)
+	}
+	storePlace(variables, dst, verdict)
+}
+
+// clearPlaces forgets verdicts of all parts of the place, which are nil until assigned.
+func clearPlaces(variables map[model.Place]model.Verdict, dst model.Place) {
+	for place := range variables {
+		if dst.Contains(place) {
+			delete(variables, place)
+		}
+	}
+}
+
+// subPlaces returns verdicts of the place and all its parts keyed by their paths relative to the place.
+func subPlaces(variables map[model.Place]model.Verdict, src model.Place) map[string]model.Verdict {
+	parts := make(map[string]model.Verdict)
+	for place, verdict := range variables {
+		if src.Contains(place) {
+			parts[place.Path[len(src.Path):]] = verdict
+		}
+	}
+	return parts
+}
+
+// resultParts returns verdicts of errors the function returns in parts of the i-th result,
+// keyed by their paths relative to the result.
+func resultParts(fn *model.Function, i int) map[string]model.Verdict {
+	prefix := strconv.Itoa(i)
+	parts := make(map[string]model.Verdict)
+	for path, verdict := range fn.Fields {
+		rest, ok := strings.CutPrefix(path, prefix)
+		if !ok || rest == "" || (rest[0] != '.' && rest[0] != '[') {
+			continue
+		}
+		parts[rest] = verdict
+	}
+	return parts
+}
+
+// resultFields returns verdicts of errors held in parts of the i-th returned value,
+// keyed by the result index and the path, as stored in function summaries.
+func (res *Result) resultFields(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	i int,
+	expr ast.Expr,
+	variables map[model.Place]model.Verdict,
+) map[string]model.Verdict {
+	fields := make(map[string]model.Verdict)
+	for path, verdict := range res.valueParts(pass, cfgs, info, expr, variables) {
+		if path != "" {
+			fields[strconv.Itoa(i)+path] = verdict
+		}
+	}
+	return fields
+}
+
+// assignRangeValue assigns elements of the ranged over slice, array, map or channel to the value variable of the loop.
+func (res *Result) assignRangeValue(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	stmt *ast.RangeStmt,
+	variables map[model.Place]model.Verdict,
+) {
+	value := stmt.Value
+	if typ := info.Types.TypeOf(stmt.X); typ != nil {
+		if _, isChan := typ.Underlying().(*types.Chan); isChan {
+			// Channels have no keys, the only variable holds elements
+			value = stmt.Key
+		}
+	}
+	if value == nil || !isContainer(info.Types.TypeOf(stmt.X)) || !containsError(info.Types.TypeOf(value)) {
+		return
+	}
+	dst, ok := placeOf(info, value)
+	if !ok {
+		return
+	}
+	parts := make(map[string]model.Verdict)
+	for path, verdict := range res.valueParts(pass, cfgs, info, stmt.X, variables) {
+		if rest, isElem := strings.CutPrefix(path, "[]"); isElem {
+			parts[rest] = verdict
+		}
+	}
+	assignParts(variables, dst, parts)
+}
+
+// isNil reports whether the expression is the nil value.
+func isNil(info *model.Info, expr ast.Expr) bool {
+	tv, ok := info.Types.Types[ast.Unparen(expr)]
+	return ok && tv.IsNil()
+}
+
+// placeName returns the name of the variable holding the place.
+func placeName(place model.Place) string {
+	if place.Object == nil {
+		return "result"
+	}
+	return place.Object.Name()
+}
@@ -2,22 +2,38 @@ package errstack
 
 import (
 	"go/ast"
-	"go/token"
+	"go/types"
 
 	"github.com/AdamBrianBright/errstack/internal/config"
 	"github.com/AdamBrianBright/errstack/internal/log"
 	"github.com/AdamBrianBright/errstack/internal/model"
-	"github.com/AdamBrianBright/errstack/internal/passes/preload_packages"
 
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/ctrlflow"
 	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
 )
 
 type Result struct {
 	OriginalFunctions   []*model.Function
-	FunctionsWithErrors map[token.Position]*model.Function
+	FunctionsWithErrors map[model.Key]*model.Function
+	callGraph           *CallGraph
+	funcValues          map[types.Object][]FuncValue
+	funcDecls           map[*types.Func]*ast.FuncDecl
+	indexedInfos        map[*types.Info]bool
 	conf                *config.Config
-	loader              *preload_packages.Result
+	pass                *analysis.Pass
+	probes              map[*types.Var]model.Verdict
+	probed              map[probeKey][]model.Verdict
+	closures            map[ast.Node]*localClosures
+	running             map[*ast.FuncLit]bool
+	recursed            int
+	effects             map[*ast.FuncLit][]closureEffect
+	globals             map[*types.Var]model.Verdict
+	captures            map[ast.Node]bool
+	stackLits           map[*ast.CompositeLit]bool
+	concreteTypes       []types.Type
+	implementers        typeutil.Map
 }
 
 // TryAddCallExpr tries to parse an AST node as a function call and add its decl to the list of functions with errors.
@@ -36,8 +52,12 @@ func (res *Result) TryAddCallExpr(info *model.Info, cfgs *ctrlflow.CFGs, call as
 		if fun == nil {
 			return nil
 		}
-		if fun.Obj != nil && fun.Obj.Decl != nil {
-			return res.TryAddFunction(info, cfgs, fun.Obj.Decl)
+		switch obj := info.Types.ObjectOf(fun).(type) {
+		case *types.Var:
+			// Function values are resolved to the functions assigned to them
+			return res.TryAddFuncValue(info, cfgs, fun, obj)
+		case *types.Func:
+			return res.TryAddObject(info, cfgs, fun, obj)
 		}
 		return nil
 	case *ast.SelectorExpr:
@@ -51,49 +71,67 @@ func (res *Result) TryAddCallExpr(info *model.Info, cfgs *ctrlflow.CFGs, call as
 			// Get the package path from the object
 			pkg := obj.Pkg()
 			if pkg != nil {
-				pkgPath := pkg.Path()
+				pkgPath := res.conf.PkgPath(pkg)
 				funcName := fun.Sel.Name
 
-				// Check if this is a known wrapper or clean function
-				if res.conf.WrapperFunctions.Match(pkgPath, funcName) || res.conf.CleanFunctions.Match(pkgPath, funcName) {
+				// Check if this is a known wrapper, clean, reset or unwrap function
+				if res.conf.WrapperFunctions.Match(pkgPath, funcName) ||
+					res.conf.CleanFunctions.Match(pkgPath, funcName) ||
+					res.conf.ResetFunctions.Match(pkgPath, funcName) ||
+					res.conf.UnwrapFunctions.Match(pkgPath, funcName) {
 					// Create a virtual function entry for external package functions
-					pos := info.Fset.Position(fun.Sel.Pos())
-					if v, ok := res.FunctionsWithErrors[pos]; ok {
+					key := model.ObjectKey(obj)
+					if v, ok := res.FunctionsWithErrors[key]; ok {
 						return v
 					}
 
+					verdict := model.Clean
+					if res.conf.WrapperFunctions.Match(pkgPath, funcName) {
+						verdict = model.AlwaysWrapping
+					}
 					fn := &model.Function{
-						Name:       funcName,
-						Node:       fun,
-						Type:       nil,
-						Body:       nil,
-						Block:      nil,
-						Pos:        pos,
-						IsWrapping: res.conf.WrapperFunctions.Match(pkgPath, funcName),
-						CalledBy:   model.Stack[*model.Function]{},
-						Pkg:        pkgPath,
-						Info:       info,
+						Name:      funcName,
+						Key:       key,
+						Node:      fun,
+						Type:      nil,
+						Body:      nil,
+						Block:     nil,
+						Pos:       info.Fset.Position(obj.Pos()),
+						Verdict:   verdict,
+						Results:   nil,
+						Fields:    nil,
+						Callbacks: nil,
+						Yields:    nil,
+						Outs:      nil,
+						Wraps:     nil,
+						CalledBy:  model.Stack[*model.Function]{},
+						Pkg:       pkgPath,
+						Info:      info,
 					}
-					res.FunctionsWithErrors[pos] = fn
+					res.FunctionsWithErrors[key] = fn
 					return fn
 				}
 			}
 		}
 
-		// Try to load the actual function declaration if it's not a known wrapper/clean function
-		if fun.Sel.Obj != nil && fun.Sel.Obj.Decl != nil {
-			return res.TryAddFunction(info, cfgs, fun.Sel.Obj.Decl)
+		// Methods called on type parameters are resolved against the instantiating types
+		if method, ok := obj.(*types.Func); ok {
+			if tp := typeParamOf(info.Types.TypeOf(fun.X)); tp != nil {
+				return res.TryAddTypeParamMethod(info, cfgs, fun, tp, method)
+			}
 		}
-		var decl ast.Node
-		if obj != nil {
-			info, decl = res.loader.LoadObject(info, obj)
-		} else {
-			info, decl = res.loader.LoadSelector(info, info.FormatNode(fun.X), fun.Sel.String())
+		// Interface methods have no body, resolve them to their implementations
+		if method, ok := obj.(*types.Func); ok && isInterfaceMethod(method) {
+			return res.TryAddInterfaceMethod(info, cfgs, fun, method)
 		}
-		if decl == nil {
-			return nil
+		switch o := obj.(type) {
+		case *types.Var:
+			// Function values stored in struct fields
+			return res.TryAddFuncValue(info, cfgs, fun, o)
+		case *types.Func:
+			return res.TryAddObject(info, cfgs, fun, o)
 		}
-		return res.TryAddFunction(info, cfgs, decl)
+		return nil
 	case *ast.StarExpr:
 		if fun.X == nil {
 			return nil
@@ -108,87 +146,280 @@ func (res *Result) TryAddCallExpr(info *model.Info, cfgs *ctrlflow.CFGs, call as
 		if fun.X == nil || fun.Index == nil {
 			return nil
 		}
+		// Function values stored in maps, slices and arrays
+		if _, ok := info.Types.TypeOf(fun.X).Underlying().(*types.Signature); !ok {
+			if v, isVar := referencedObject(info, fun.X).(*types.Var); isVar {
+				return res.TryAddFuncValue(info, cfgs, fun, v)
+			}
+			return nil
+		}
+		return res.TryAddCallExpr(info, cfgs, fun.X)
+	case *ast.FuncLit:
+		// Function literals called in place
+		return res.TryAddFunction(info, cfgs, fun)
+	case *ast.IndexListExpr:
+		// Instantiations of generic functions with multiple type parameters
+		if fun.X == nil {
+			return nil
+		}
 		return res.TryAddCallExpr(info, cfgs, fun.X)
 	}
 	return nil
 }
 
 // TryAddFunction tries to parse an AST node as a function and add it to the list of functions with errors.
+// Functions returning errors in fields or elements of their results are added as well, along with
+// iterators yielding errors and functions returning such iterators.
 // Returns the position of the function declaration if it was added successfully, nil otherwise.
 // If a function is already in the list, it returns the existing position.
 func (res *Result) TryAddFunction(info *model.Info, cfgs *ctrlflow.CFGs, fun any) *model.Function {
 	switch decl := fun.(type) {
 	case *ast.FuncDecl:
-		if decl.Type.Results == nil {
+		obj := info.Types.Defs[decl.Name]
+		if obj == nil {
 			return nil
 		}
-		pos := info.Fset.Position(decl.Pos())
-		if v, ok := res.FunctionsWithErrors[pos]; ok {
+		key := model.ObjectKey(obj)
+		if v, ok := res.FunctionsWithErrors[key]; ok {
 			return v
 		}
 
-		var foundError bool
-		for _, f := range decl.Type.Results.List {
-			if fieldType := info.Types.TypeOf(f.Type); isErrorType(fieldType) {
-				foundError = true
-				break
-			}
-		}
-		if !foundError {
+		if sig, ok := obj.Type().(*types.Signature); !ok || !hasErrors(sig) {
 			return nil
 		}
 		fn := &model.Function{
-			Name:       decl.Name.Name,
-			Node:       decl,
-			Type:       decl.Type,
-			Body:       decl.Body,
-			Block:      getCFGBlock(cfgs, decl),
-			Pos:        pos,
-			IsWrapping: false,
-			CalledBy:   model.Stack[*model.Function]{},
-			Pkg:        res.conf.GetPkgPath(info.Fset.Position(decl.Pos()).Filename),
-			Info:       info,
-		}
-		res.FunctionsWithErrors[pos] = fn
+			Name:      decl.Name.Name,
+			Key:       key,
+			Node:      decl,
+			Type:      decl.Type,
+			Body:      decl.Body,
+			Block:     getCFGBlock(cfgs, decl),
+			Pos:       info.Fset.Position(decl.Pos()),
+			Verdict:   model.Clean,
+			Results:   nil,
+			Fields:    nil,
+			Callbacks: nil,
+			Yields:    nil,
+			Outs:      nil,
+			Wraps:     nil,
+			CalledBy:  model.Stack[*model.Function]{},
+			Pkg:       res.conf.PkgPath(obj.Pkg()),
+			Info:      info,
+		}
+		res.FunctionsWithErrors[key] = fn
 		return fn
 	case *ast.FuncLit:
-		if decl.Type.Results == nil {
-			return nil
-		}
-		pos := info.Fset.Position(decl.Pos())
-		if v, ok := res.FunctionsWithErrors[pos]; ok {
+		key := model.LitKey(decl)
+		if v, ok := res.FunctionsWithErrors[key]; ok {
 			return v
 		}
 
-		var foundError bool
-		for _, f := range decl.Type.Results.List {
-			if fieldType := info.Types.TypeOf(f.Type); isErrorType(fieldType) {
-				foundError = true
-				break
-			}
-		}
-		if !foundError {
+		if sig, ok := info.Types.TypeOf(decl).(*types.Signature); !ok || !hasErrors(sig) {
 			return nil
 		}
 		fn := &model.Function{
-			Name:       "anonymous",
-			Node:       decl,
-			Type:       decl.Type,
-			Body:       decl.Body,
-			Block:      getCFGBlock(cfgs, decl),
-			Pos:        pos,
-			IsWrapping: false,
-			CalledBy:   model.Stack[*model.Function]{},
-			Pkg:        res.conf.GetPkgPath(info.Fset.Position(decl.Pos()).Filename),
-			Info:       info,
-		}
-		res.FunctionsWithErrors[pos] = fn
+			Name:      "anonymous",
+			Key:       key,
+			Node:      decl,
+			Type:      decl.Type,
+			Body:      decl.Body,
+			Block:     getCFGBlock(cfgs, decl),
+			Pos:       info.Fset.Position(decl.Pos()),
+			Verdict:   model.Clean,
+			Results:   nil,
+			Fields:    nil,
+			Callbacks: nil,
+			Yields:    nil,
+			Outs:      nil,
+			Wraps:     nil,
+			CalledBy:  model.Stack[*model.Function]{},
+			Pkg:       res.conf.PkgPath(info.Pkg),
+			Info:      info,
+		}
+		res.FunctionsWithErrors[key] = fn
 		return fn
 	}
 
 	return nil
 }
 
+// TryAddInterfaceMethod adds a virtual function for the interface method and links it with all its
+// implementations found in loaded packages, so it is considered wrapping if any implementation is.
+// Returns nil if the method does not return errors.
+func (res *Result) TryAddInterfaceMethod(info *model.Info, cfgs *ctrlflow.CFGs, sel *ast.SelectorExpr, method *types.Func) *model.Function {
+	sig, ok := method.Type().(*types.Signature)
+	if !ok || !hasErrors(sig) {
+		return nil
+	}
+	key := model.ObjectKey(method)
+	if v, ok := res.FunctionsWithErrors[key]; ok {
+		return v
+	}
+	pos := info.Fset.Position(method.Pos())
+
+	fn := &model.Function{
+		Name:      method.Name(),
+		Key:       key,
+		Node:      sel,
+		Type:      nil,
+		Body:      nil,
+		Block:     nil,
+		Pos:       pos,
+		Verdict:   model.Clean,
+		Results:   nil,
+		Fields:    nil,
+		Callbacks: nil,
+		Yields:    nil,
+		Outs:      nil,
+		Wraps:     nil,
+		CalledBy:  model.Stack[*model.Function]{},
+		Targets:   model.Stack[*model.Function]{},
+		Partial:   false,
+		Pkg:       res.conf.PkgPath(method.Pkg()),
+		Info:      info,
+	}
+	res.FunctionsWithErrors[key] = fn
+
+	fn.Partial = !res.knowsAllImplementations(method)
+	for _, impl := range res.findImplementations(method) {
+		implFn := res.TryAddObject(info, cfgs, sel, impl)
+		if implFn == nil {
+			// The implementation has neither source nor facts
+			fn.Partial = true
+			continue
+		}
+		log.Log("Interface method %s is implemented by %s: %s\n", fn.Name, implFn.Name, implFn.Pos.String())
+		// The interface method returns whatever its implementations return
+		implFn.CalledBy.AddUnique(fn)
+		fn.Targets.AddUnique(implFn)
+	}
+	if len(fn.Targets) == 0 || fn.Partial {
+		// None or not all of the implementations are known
+		fn.Verdict = res.unknownVerdict()
+	}
+
+	return fn
+}
+
+// findImplementations finds all concrete methods implementing the given interface method
+// in the analyzed package and all packages it imports, see knowsAllImplementations.
+func (res *Result) findImplementations(method *types.Func) []*types.Func {
+	iface := receiverInterface(method)
+	if iface == nil {
+		return nil
+	}
+
+	var found []*types.Func
+	seen := make(map[*types.Func]bool)
+	for _, typ := range res.implementersOf(iface) {
+		sel, _, _ := types.LookupFieldOrMethod(typ, false, method.Pkg(), method.Name())
+		impl, isFunc := sel.(*types.Func)
+		if !isFunc || seen[impl] {
+			continue
+		}
+		seen[impl] = true
+		log.Log("Found implementation %s of %s\n", impl.FullName(), method.FullName())
+		found = append(found, impl)
+	}
+
+	return found
+}
+
+// implementersOf returns the concrete types and pointers to them implementing the interface.
+// Scopes of the imported packages are scanned once per pass and implementers are cached per interface,
+// so resolving every method of an interface does not check every type again.
+func (res *Result) implementersOf(iface *types.Interface) []types.Type {
+	if cached, ok := res.implementers.At(iface).([]types.Type); ok {
+		return cached
+	}
+	if res.concreteTypes == nil {
+		res.concreteTypes = []types.Type{}
+		for _, pkg := range importedPackages(res.pass.Pkg) {
+			scope := pkg.Scope()
+			for _, name := range scope.Names() {
+				obj, isType := scope.Lookup(name).(*types.TypeName)
+				if !isType || obj.IsAlias() || types.IsInterface(obj.Type()) {
+					continue
+				}
+				res.concreteTypes = append(res.concreteTypes, obj.Type(), types.NewPointer(obj.Type()))
+			}
+		}
+	}
+
+	implementers := []types.Type{}
+	for _, typ := range res.concreteTypes {
+		if types.Implements(typ, iface) {
+			implementers = append(implementers, typ)
+		}
+	}
+	res.implementers.Set(iface, implementers)
+	return implementers
+}
+
+// knowsAllImplementations reports whether findImplementations finds all implementations of the interface method.
+// Main packages import the whole program. Implementations of interfaces used by other packages may be declared
+// in packages importing them, e.g. when they are injected as dependencies, unless the interface has unexported
+// methods, so only types of its own package or types embedding them implement it.
+func (res *Result) knowsAllImplementations(method *types.Func) bool {
+	if res.pass.Pkg.Name() == "main" {
+		return true
+	}
+	iface := receiverInterface(method)
+	if iface == nil {
+		return false
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		if !iface.Method(i).Exported() {
+			return true
+		}
+	}
+	return false
+}
+
+// receiverInterface returns the interface declaring the method, nil if it is not an interface method.
+func receiverInterface(method *types.Func) *types.Interface {
+	sig, ok := method.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return nil
+	}
+	iface, _ := sig.Recv().Type().Underlying().(*types.Interface)
+	return iface
+}
+
+// importedPackages returns the package along with all packages it transitively imports.
+func importedPackages(pkg *types.Package) []*types.Package {
+	pkgs := []*types.Package{pkg}
+	seen := map[*types.Package]bool{pkg: true}
+	for i := 0; i < len(pkgs); i++ {
+		for _, imp := range pkgs[i].Imports() {
+			if !seen[imp] {
+				seen[imp] = true
+				pkgs = append(pkgs, imp)
+			}
+		}
+	}
+	return pkgs
+}
+
+// isInterfaceMethod reports whether the function is an abstract method of an interface.
+func isInterfaceMethod(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return false
+	}
+	return types.IsInterface(sig.Recv().Type())
+}
+
+// hasErrorResult reports whether any of the signature results is an error.
+func hasErrorResult(sig *types.Signature) bool {
+	for i := 0; i < sig.Results().Len(); i++ {
+		if isErrorType(sig.Results().At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
 // getCFGBlock returns the first block of the CFG for the given node.
 func getCFGBlock(cfgs *ctrlflow.CFGs, node ast.Node) *cfg.Block {
 	defer func() {
@@ -0,0 +1,637 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// SSAEngine tracks error values through the SSA form of the package functions.
+// Unlike the AST engine, phi nodes, loads and stores, closures and tuple extracts
+// are handled uniformly by following SSA values back to the calls producing them.
+type SSAEngine struct {
+	res       *Result
+	pass      *analysis.Pass
+	info      *model.Info
+	pkg       *ssa.Package
+	funcs     []*ssa.Function
+	objects   map[*types.Func]*ssa.Function
+	results   map[*ssa.Function][]bool
+	always    map[*ssa.Function]bool
+	alwaysAt  map[*ssa.Function][]bool
+	callbacks map[*ssa.Function][]int
+	wraps     map[*ssa.Function][]int
+	probe     *callbackProbe
+	calls     map[token.Pos]*ast.CallExpr
+}
+
+// RunSSA computes wrapping summaries of all package functions using SSA,
+// reports unnecessary wrapping and exports facts for exported functions.
+// SSA is built by running buildssa.Analyzer here instead of requiring it, so the default AST engine
+// does not pay for building SSA of every analyzed package and builder panics are recovered by run.
+func (res *Result) RunSSA(pass *analysis.Pass) error {
+	built, err := buildssa.Analyzer.Run(pass)
+	if err != nil {
+		return err
+	}
+	pkg, funcs := built.(*buildssa.SSA).Pkg, built.(*buildssa.SSA).SrcFuncs
+	engine := &SSAEngine{
+		res:       res,
+		pass:      pass,
+		info:      model.NewInfo(pass),
+		pkg:       pkg,
+		funcs:     funcs,
+		objects:   make(map[*types.Func]*ssa.Function, len(funcs)),
+		results:   make(map[*ssa.Function][]bool, len(funcs)),
+		always:    make(map[*ssa.Function]bool, len(funcs)),
+		alwaysAt:  make(map[*ssa.Function][]bool, len(funcs)),
+		callbacks: make(map[*ssa.Function][]int),
+		wraps:     make(map[*ssa.Function][]int),
+		probe:     nil,
+		calls:     make(map[token.Pos]*ast.CallExpr),
+	}
+	for _, fn := range engine.funcs {
+		if obj, ok := fn.Object().(*types.Func); ok {
+			engine.objects[obj] = fn
+		}
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				engine.calls[call.Lparen] = call
+			}
+			return true
+		})
+	}
+
+	log.Log("SSA SummarizeCallbacks\n")
+	engine.SummarizeCallbacks()
+	log.Log("SSA Summarize\n")
+	engine.Summarize()
+	log.Log("SSA SummarizeAlways\n")
+	engine.SummarizeAlways()
+	log.Log("SSA SummarizeWrappers\n")
+	engine.SummarizeWrappers()
+	log.Log("SSA Report\n")
+	engine.Report()
+	log.Log("SSA ExportFacts\n")
+	engine.ExportFacts()
+	return nil
+}
+
+// Summarize marks results of package functions that return errors with stacktraces.
+// Summaries only change from false to true, so iterating until nothing changes
+// handles recursive functions.
+func (e *SSAEngine) Summarize() {
+	for _, fn := range e.funcs {
+		e.results[fn] = make([]bool, fn.Signature.Results().Len())
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range e.funcs {
+			results := e.results[fn]
+			for i, carries := range e.summarize(fn) {
+				if !carries || results[i] {
+					continue
+				}
+				log.Log("SSA function %s is wrapping in result %d\n", fn.String(), i)
+				results[i] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// summarize reports for every result of the function whether errors returned as it may carry a stacktrace.
+func (e *SSAEngine) summarize(fn *ssa.Function) []bool {
+	results := make([]bool, fn.Signature.Results().Len())
+	if obj, ok := fn.Object().(*types.Func); ok {
+		pkg, name := e.res.conf.PkgPath(obj.Pkg()), obj.Name()
+		if e.res.conf.CleanFunctions.Match(pkg, name) || e.res.conf.ResetFunctions.Match(pkg, name) {
+			return results
+		}
+		if e.res.conf.WrapperFunctions.Match(pkg, name) || e.res.isInferredWrapper(obj) {
+			for i := range results {
+				results[i] = isErrorType(fn.Signature.Results().At(i).Type())
+			}
+			return results
+		}
+	}
+	for _, block := range fn.Blocks {
+		ret, ok := block.Instrs[len(block.Instrs)-1].(*ssa.Return)
+		if !ok {
+			continue
+		}
+		for i, result := range ret.Results {
+			if !results[i] && isErrorType(result.Type()) && e.carriesStack(result, map[ssa.Value]bool{}) {
+				results[i] = true
+			}
+		}
+	}
+	return results
+}
+
+// Report reports calls of wrapper functions and helpers with errors already carrying stacktraces.
+func (e *SSAEngine) Report() {
+	cfgs := e.pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+	for _, fn := range e.funcs {
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				call, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				args := e.wrappedArgs(call.Common())
+				if len(args) == 0 {
+					continue
+				}
+				pkg, name, _ := e.calleeName(call.Common())
+				verdict := model.Clean
+				for _, arg := range args {
+					if !isErrorType(arg.Type()) {
+						continue
+					}
+					if e.alwaysCarriesStack(arg, map[ssa.Value]bool{}) {
+						verdict = model.AlwaysWrapping
+						break
+					}
+					if e.carriesStack(arg, map[ssa.Value]bool{}) {
+						verdict = model.Wrapping
+					}
+				}
+				node := e.calls[call.Common().Pos()]
+				if !verdict.IsWrapping() || node == nil {
+					continue
+				}
+				e.res.reportUnnecessaryWrapping(e.pass, cfgs, e.info, &model.Function{Name: name, Pkg: pkg}, node, verdict)
+			}
+		}
+	}
+}
+
+// ExportFacts exports wrapping facts for all exported package functions returning errors and exported error variables.
+func (e *SSAEngine) ExportFacts() {
+	for _, fn := range e.funcs {
+		obj, ok := fn.Object().(*types.Func)
+		if !ok || obj.Pkg() != e.pass.Pkg || !obj.Exported() || !hasErrors(fn.Signature) {
+			continue
+		}
+		verdict := model.Clean
+		if e.always[fn] {
+			verdict = model.AlwaysWrapping
+		} else if resultCarries(e.results[fn], anyResult) {
+			verdict = model.Wrapping
+		}
+		results := make([]model.Verdict, len(e.results[fn]))
+		for i, carries := range e.results[fn] {
+			if e.alwaysAt[fn][i] {
+				results[i] = model.AlwaysWrapping
+			} else if carries {
+				results[i] = model.Wrapping
+			}
+		}
+		yields := e.yieldFacts(fn)
+		for _, yielded := range yields {
+			// Iterators are wrapping if they yield errors with stacktraces
+			verdict = verdict.Max(yielded)
+		}
+		e.pass.ExportObjectFact(obj, &WrappingFact{
+			Verdict:   verdict,
+			Results:   results,
+			Callbacks: e.callbackFacts(fn),
+			Yields:    yields,
+			Outs:      e.outFacts(fn),
+			Inferred:  e.res.isInferredWrapper(obj),
+			Wraps:     e.wraps[fn],
+		})
+	}
+	e.exportGlobalFacts()
+}
+
+// carriesStack reports whether the value may be an error with a stacktrace.
+func (e *SSAEngine) carriesStack(v ssa.Value, visited map[ssa.Value]bool) bool {
+	if v == nil || visited[v] {
+		return false
+	}
+	visited[v] = true
+
+	switch value := v.(type) {
+	case *ssa.Call:
+		return e.callCarriesStack(value.Common(), 0, visited)
+	case *ssa.Extract:
+		// Results of calls are summarized separately, other tuples hold a single error at most
+		if call, ok := value.Tuple.(*ssa.Call); ok {
+			return e.callCarriesStack(call.Common(), value.Index, visited)
+		}
+		return e.carriesStack(value.Tuple, visited)
+	case *ssa.Phi:
+		for _, edge := range value.Edges {
+			if e.carriesStack(edge, visited) {
+				return true
+			}
+		}
+	case *ssa.MakeInterface:
+		return e.carriesStack(value.X, visited)
+	case *ssa.ChangeInterface:
+		return e.carriesStack(value.X, visited)
+	case *ssa.ChangeType:
+		return e.carriesStack(value.X, visited)
+	case *ssa.TypeAssert:
+		return e.carriesStack(value.X, visited)
+	case *ssa.Alloc:
+		return e.allocCarriesStack(value)
+	case *ssa.UnOp:
+		switch value.Op {
+		case token.MUL:
+			if alloc, ok := value.X.(*ssa.Alloc); ok && e.allocCarriesStack(alloc) {
+				return true
+			}
+			return e.addressCarriesStack(value.X, value, visited)
+		case token.ARROW:
+			return e.channelCarriesStack(value.X, visited)
+		}
+	case *ssa.Lookup:
+		return e.addressCarriesStack(value.X, value, visited)
+	case *ssa.Parameter:
+		return e.yieldedCarriesStack(value)
+	}
+	return false
+}
+
+// addressCarriesStack reports whether any value stored to the address before the load may carry a stacktrace.
+// Fields are tracked by their index, so stores into other fields of the same struct are ignored.
+// A nil load stands for the end of the function, e.g. for values written through pointer parameters.
+func (e *SSAEngine) addressCarriesStack(addr ssa.Value, load ssa.Instruction, visited map[ssa.Value]bool) bool {
+	if global, ok := addr.(*ssa.Global); ok {
+		return e.globalCarriesStack(global, visited)
+	}
+	for _, alias := range e.aliases(addr) {
+		refs := alias.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			if !mayPrecede(ref, load) {
+				continue
+			}
+			switch instr := ref.(type) {
+			case *ssa.Store:
+				if instr.Addr == alias && e.carriesStack(instr.Val, visited) {
+					return true
+				}
+			case *ssa.MapUpdate:
+				if instr.Map == alias && e.carriesStack(instr.Value, visited) {
+					return true
+				}
+			case *ssa.MakeInterface:
+				// Unwrap functions store errors into targets passed as interfaces
+				if instr.X == alias && isErrorTarget(alias.Type()) && e.targetCarriesStack(instr, visited) {
+					return true
+				}
+			case ssa.CallInstruction:
+				// Callees may write errors through pointers they are passed
+				for i, arg := range instr.Common().Args {
+					if arg == alias && e.outCarriesStack(instr.Common(), i, visited) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// mayPrecede reports whether the instruction may run before the load, so values it stores may be loaded.
+// Instructions of other functions, e.g. closures capturing the variable, may run at any time.
+// A nil load stands for the end of the function, which every instruction may precede.
+func mayPrecede(instr, load ssa.Instruction) bool {
+	if load == nil || instr.Parent() != load.Parent() {
+		return true
+	}
+	from, to := instr.Block(), load.Block()
+	if from == to && slices.Index(from.Instrs, instr) < slices.Index(to.Instrs, load) {
+		return true
+	}
+	// Otherwise the load must be reachable from the block, through a loop if it is the same one
+	seen := make(map[*ssa.BasicBlock]bool)
+	queue := slices.Clone(from.Succs)
+	for len(queue) > 0 {
+		block := queue[0]
+		queue = queue[1:]
+		if block == to {
+			return true
+		}
+		if seen[block] {
+			continue
+		}
+		seen[block] = true
+		queue = append(queue, block.Succs...)
+	}
+	return false
+}
+
+// aliases returns all values referring to the same memory as the address: the address itself,
+// free variables of closures capturing it, same field or element addresses of the same value
+// and other loads of the same address.
+func (e *SSAEngine) aliases(addr ssa.Value) []ssa.Value {
+	var aliases []ssa.Value
+	seen := make(map[ssa.Value]bool)
+	var visit func(v ssa.Value)
+	visit = func(v ssa.Value) {
+		if v == nil || seen[v] {
+			return
+		}
+		seen[v] = true
+		aliases = append(aliases, v)
+
+		switch value := v.(type) {
+		case *ssa.FreeVar:
+			// Captured variables are bound when the closure is created
+			for _, binding := range e.bindings(value) {
+				visit(binding)
+			}
+		case *ssa.FieldAddr:
+			visitSiblings(value.X, func(sibling ssa.Instruction) {
+				if f, ok := sibling.(*ssa.FieldAddr); ok && f.Field == value.Field {
+					visit(f)
+				}
+			})
+		case *ssa.IndexAddr:
+			// Slice literals are backed by arrays, so elements are addressed through both
+			base := value.X
+			if slice, ok := base.(*ssa.Slice); ok {
+				base = slice.X
+			}
+			var visitElements func(instr ssa.Instruction)
+			visitElements = func(instr ssa.Instruction) {
+				switch sibling := instr.(type) {
+				case *ssa.IndexAddr:
+					visit(sibling)
+				case *ssa.Slice:
+					visitSiblings(sibling, visitElements)
+				}
+			}
+			visitSiblings(base, visitElements)
+		case *ssa.UnOp:
+			// Loaded maps and pointers are the same as other loads of the same address
+			if value.Op == token.MUL {
+				visitSiblings(value.X, func(sibling ssa.Instruction) {
+					if u, ok := sibling.(*ssa.UnOp); ok && u.Op == token.MUL {
+						visit(u)
+					}
+				})
+			}
+		}
+
+		refs := v.Referrers()
+		if refs == nil {
+			return
+		}
+		for _, ref := range *refs {
+			closure, ok := ref.(*ssa.MakeClosure)
+			if !ok {
+				continue
+			}
+			fn := closure.Fn.(*ssa.Function)
+			for i, binding := range closure.Bindings {
+				if binding == v && i < len(fn.FreeVars) {
+					visit(fn.FreeVars[i])
+				}
+			}
+		}
+	}
+	visit(addr)
+	return aliases
+}
+
+// visitSiblings calls the visitor for every instruction referring to the value.
+func visitSiblings(v ssa.Value, visitor func(ssa.Instruction)) {
+	refs := v.Referrers()
+	if refs == nil {
+		return
+	}
+	for _, ref := range *refs {
+		visitor(ref)
+	}
+}
+
+// bindings returns values bound to the free variable by all closures creating its function.
+func (e *SSAEngine) bindings(freeVar *ssa.FreeVar) []ssa.Value {
+	fn := freeVar.Parent()
+	index := -1
+	for i, v := range fn.FreeVars {
+		if v == freeVar {
+			index = i
+		}
+	}
+	if index < 0 || fn.Parent() == nil {
+		return nil
+	}
+	var bound []ssa.Value
+	for _, block := range fn.Parent().Blocks {
+		for _, instr := range block.Instrs {
+			if closure, ok := instr.(*ssa.MakeClosure); ok && closure.Fn == fn && index < len(closure.Bindings) {
+				bound = append(bound, closure.Bindings[index])
+			}
+		}
+	}
+	return bound
+}
+
+// callCarriesStack reports whether the call may return an error with a stacktrace as the result with the given index.
+func (e *SSAEngine) callCarriesStack(call *ssa.CallCommon, index int, visited map[ssa.Value]bool) bool {
+	if e.probe != nil {
+		return e.callReturnsProbe(call)
+	}
+	if e.isUnwrap(call) {
+		return e.unwrapCarriesStack(call, visited)
+	}
+	if pkg, name, ok := e.calleeName(call); ok {
+		if e.res.conf.CleanFunctions.Match(pkg, name) || e.res.conf.ResetFunctions.Match(pkg, name) {
+			return false
+		}
+		if e.res.conf.WrapperFunctions.Match(pkg, name) {
+			return true
+		}
+	}
+	if e.groupCarriesStack(call) {
+		return true
+	}
+
+	if call.IsInvoke() {
+		for _, impl := range e.res.findImplementations(call.Method) {
+			if e.funcCarriesStack(impl, index) {
+				return true
+			}
+		}
+		// Implementations declared in packages importing this one are unknown callees
+		return !e.res.knowsAllImplementations(call.Method) && e.res.unknownVerdict() == model.Wrapping
+	}
+	for _, callee := range e.funcValues(call.Value, map[ssa.Value]bool{}) {
+		if e.resultSummary(callee, index) || e.callbacksCarryStack(callee, call) {
+			return true
+		}
+	}
+	// Functions assigned by packages importing this one are unknown callees
+	return e.partialFuncValue(call.Value) && e.res.unknownVerdict() == model.Wrapping
+}
+
+// funcCarriesStack returns the summary of the result with the given index of a package function
+// or the fact of an imported one.
+func (e *SSAEngine) funcCarriesStack(obj *types.Func, index int) bool {
+	if obj.Pkg() == e.pass.Pkg {
+		return resultCarries(e.results[e.objects[obj.Origin()]], index)
+	}
+	var fact WrappingFact
+	if !e.pass.ImportObjectFact(obj, &fact) {
+		log.Log("SSA no fact for function %s\n", obj.FullName())
+		return e.res.unknownVerdict() == model.Wrapping
+	}
+	if index != anyResult && index < len(fact.Results) {
+		return fact.Results[index].IsWrapping()
+	}
+	return fact.Verdict.IsWrapping()
+}
+
+// summary returns the wrapping summary of the function.
+func (e *SSAEngine) summary(fn *ssa.Function) bool {
+	return e.resultSummary(fn, anyResult)
+}
+
+// resultSummary returns the wrapping summary of the result with the given index of the function.
+func (e *SSAEngine) resultSummary(fn *ssa.Function, index int) bool {
+	if fn.Origin() != nil {
+		fn = fn.Origin()
+	}
+	if results, ok := e.results[fn]; ok {
+		return resultCarries(results, index)
+	}
+	if fn.Synthetic == "" && fn.Pkg != nil && fn.Pkg.Pkg == e.pass.Pkg {
+		return false
+	}
+	// Imported functions and synthetic wrappers of declared methods
+	if obj, ok := fn.Object().(*types.Func); ok {
+		return e.funcCarriesStack(obj, index)
+	}
+	return false
+}
+
+// anyResult stands for any result of a function in per-result lookups.
+const anyResult = -1
+
+// resultCarries reports whether the result with the given index, or any result for anyResult, carries a stacktrace.
+func resultCarries(results []bool, index int) bool {
+	if index == anyResult || index >= len(results) {
+		return slices.Contains(results, true)
+	}
+	return results[index]
+}
+
+// funcValues returns all functions the value may hold.
+func (e *SSAEngine) funcValues(v ssa.Value, visited map[ssa.Value]bool) []*ssa.Function {
+	if v == nil || visited[v] {
+		return nil
+	}
+	visited[v] = true
+
+	switch value := v.(type) {
+	case *ssa.Function:
+		return []*ssa.Function{value}
+	case *ssa.MakeClosure:
+		return e.funcValues(value.Fn, visited)
+	case *ssa.Phi:
+		var fns []*ssa.Function
+		for _, edge := range value.Edges {
+			fns = append(fns, e.funcValues(edge, visited)...)
+		}
+		return fns
+	case *ssa.ChangeType:
+		return e.funcValues(value.X, visited)
+	case *ssa.Call:
+		// Functions returned by sync.OnceValues and alike return what their argument returns
+		if callee := value.Common().StaticCallee(); callee != nil && len(value.Common().Args) == 1 {
+			if obj, ok := callee.Object().(*types.Func); ok && e.res.isOnceFunction(obj) {
+				return e.funcValues(value.Common().Args[0], visited)
+			}
+		}
+	case *ssa.UnOp, *ssa.Lookup:
+		var addr ssa.Value
+		if unOp, ok := value.(*ssa.UnOp); ok {
+			if unOp.Op != token.MUL {
+				return nil
+			}
+			addr = unOp.X
+		} else {
+			addr = value.(*ssa.Lookup).X
+		}
+		var fns []*ssa.Function
+		for _, alias := range e.aliases(addr) {
+			visitSiblings(alias, func(ref ssa.Instruction) {
+				switch instr := ref.(type) {
+				case *ssa.Store:
+					if instr.Addr == alias {
+						fns = append(fns, e.funcValues(instr.Val, visited)...)
+					}
+				case *ssa.MapUpdate:
+					if instr.Map == alias {
+						fns = append(fns, e.funcValues(instr.Value, visited)...)
+					}
+				}
+			})
+		}
+		return fns
+	}
+	return nil
+}
+
+// partialFuncValue reports whether the called value is loaded from a variable or field
+// that may hold functions the analysis does not see, see knowsAllValues.
+func (e *SSAEngine) partialFuncValue(v ssa.Value) bool {
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return false
+	}
+	switch addr := load.X.(type) {
+	case *ssa.Global:
+		global, isVar := addr.Object().(*types.Var)
+		return isVar && !e.res.knowsAllValues(global)
+	case *ssa.FieldAddr:
+		ptr, isPtr := addr.X.Type().Underlying().(*types.Pointer)
+		if !isPtr {
+			return false
+		}
+		st, isStruct := ptr.Elem().Underlying().(*types.Struct)
+		return isStruct && !e.res.knowsAllValues(st.Field(addr.Field))
+	}
+	return false
+}
+
+// calleeName returns the package and the name of the statically called function.
+func (e *SSAEngine) calleeName(call *ssa.CallCommon) (string, string, bool) {
+	callee := call.StaticCallee()
+	if callee == nil {
+		return "", "", false
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return "", "", false
+	}
+	return e.res.conf.PkgPath(obj.Pkg()), obj.Name(), true
+}
+
+// isWrapper reports whether the function is listed in WrapperFunctions or inferred to be a wrapper.
+func (e *SSAEngine) isWrapper(obj *types.Func) bool {
+	if obj == nil {
+		return false
+	}
+	return e.res.conf.WrapperFunctions.Match(e.res.conf.PkgPath(obj.Pkg()), obj.Name()) || e.res.isInferredWrapper(obj)
+}
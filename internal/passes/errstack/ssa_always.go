@@ -0,0 +1,194 @@
+package errstack
+
+import (
+	"go/token"
+	"go/types"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// SummarizeAlways marks package functions and their results that return errors with stacktraces on all paths.
+// Summaries start false and only change to true once all returned errors are known to carry
+// stacktraces, so recursive functions never claim stacktraces they don't have.
+func (e *SSAEngine) SummarizeAlways() {
+	for _, fn := range e.funcs {
+		e.alwaysAt[fn] = make([]bool, len(e.results[fn]))
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range e.funcs {
+			if e.always[fn] || !e.summary(fn) {
+				continue
+			}
+			results, always := e.summarizeAlways(fn)
+			for i, carries := range results {
+				if carries && !e.alwaysAt[fn][i] {
+					e.alwaysAt[fn][i] = true
+					changed = true
+				}
+			}
+			if always {
+				log.Log("SSA function %s is always wrapping\n", fn.String())
+				e.always[fn] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// summarizeAlways reports for every result of the function whether all non-nil errors returned as it
+// carry stacktraces, and whether all non-nil errors returned by the function do.
+func (e *SSAEngine) summarizeAlways(fn *ssa.Function) ([]bool, bool) {
+	results := make([]bool, fn.Signature.Results().Len())
+	if obj, ok := fn.Object().(*types.Func); ok {
+		pkg, name := e.res.conf.PkgPath(obj.Pkg()), obj.Name()
+		if e.res.conf.WrapperFunctions.Match(pkg, name) || e.res.isInferredWrapper(obj) {
+			for i := range results {
+				results[i] = isErrorType(fn.Signature.Results().At(i).Type())
+			}
+			return results, true
+		}
+	}
+	failed := make([]bool, len(results))
+	for _, block := range fn.Blocks {
+		ret, ok := block.Instrs[len(block.Instrs)-1].(*ssa.Return)
+		if !ok {
+			continue
+		}
+		for i, result := range ret.Results {
+			if !isErrorType(result.Type()) || isNilConst(result) || failed[i] {
+				continue
+			}
+			if e.alwaysCarriesStack(result, map[ssa.Value]bool{}) {
+				results[i] = true
+			} else {
+				results[i], failed[i] = false, true
+			}
+		}
+	}
+	return results, slices.Contains(results, true) && !slices.Contains(failed, true)
+}
+
+// alwaysCarriesStack reports whether the value is an error with a stacktrace on all paths.
+// Nil constants are ignored, since nil errors carry nothing to wrap.
+func (e *SSAEngine) alwaysCarriesStack(v ssa.Value, visited map[ssa.Value]bool) bool {
+	if v == nil {
+		return false
+	}
+	if done, ok := visited[v]; ok {
+		// Cycles through phi nodes don't add new values
+		return done
+	}
+	visited[v] = true
+	result := e.valueAlwaysCarriesStack(v, visited)
+	visited[v] = result
+	return result
+}
+
+func (e *SSAEngine) valueAlwaysCarriesStack(v ssa.Value, visited map[ssa.Value]bool) bool {
+	switch value := v.(type) {
+	case *ssa.Call:
+		return e.callAlwaysCarriesStack(value.Common(), 0, visited)
+	case *ssa.Extract:
+		if call, ok := value.Tuple.(*ssa.Call); ok {
+			return e.callAlwaysCarriesStack(call.Common(), value.Index, visited)
+		}
+		return e.alwaysCarriesStack(value.Tuple, visited)
+	case *ssa.Phi:
+		var found bool
+		for _, edge := range value.Edges {
+			if isNilConst(edge) {
+				continue
+			}
+			if !e.alwaysCarriesStack(edge, visited) {
+				return false
+			}
+			found = true
+		}
+		return found
+	case *ssa.MakeInterface:
+		return e.alwaysCarriesStack(value.X, visited)
+	case *ssa.ChangeInterface:
+		return e.alwaysCarriesStack(value.X, visited)
+	case *ssa.ChangeType:
+		return e.alwaysCarriesStack(value.X, visited)
+	case *ssa.UnOp:
+		if value.Op == token.MUL {
+			return e.addressAlwaysCarriesStack(value.X, value, visited)
+		}
+	}
+	return false
+}
+
+// addressAlwaysCarriesStack reports whether all values stored to the address before the load carry stacktraces.
+func (e *SSAEngine) addressAlwaysCarriesStack(addr ssa.Value, load ssa.Instruction, visited map[ssa.Value]bool) bool {
+	var found bool
+	for _, alias := range e.aliases(addr) {
+		refs := alias.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			store, ok := ref.(*ssa.Store)
+			if !ok || store.Addr != alias || isNilConst(store.Val) || !mayPrecede(store, load) {
+				continue
+			}
+			if !e.alwaysCarriesStack(store.Val, visited) {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// callAlwaysCarriesStack reports whether the statically called function returns errors
+// with stacktraces on all paths as the result with the given index. Dynamic calls are never certain.
+func (e *SSAEngine) callAlwaysCarriesStack(call *ssa.CallCommon, index int, visited map[ssa.Value]bool) bool {
+	if e.isUnwrap(call) {
+		return e.unwrapAlwaysCarriesStack(call, visited)
+	}
+	if pkg, name, ok := e.calleeName(call); ok {
+		if e.res.conf.CleanFunctions.Match(pkg, name) || e.res.conf.ResetFunctions.Match(pkg, name) {
+			return false
+		}
+		if e.res.conf.WrapperFunctions.Match(pkg, name) {
+			return true
+		}
+	}
+	callee := call.StaticCallee()
+	if callee == nil {
+		return false
+	}
+	if callee.Origin() != nil {
+		callee = callee.Origin()
+	}
+	if callee.Pkg != nil && callee.Pkg.Pkg == e.pass.Pkg {
+		if results := e.alwaysAt[callee]; index < len(results) {
+			return results[index]
+		}
+		return e.always[callee]
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return false
+	}
+	var fact WrappingFact
+	if !e.pass.ImportObjectFact(obj, &fact) {
+		return false
+	}
+	if index < len(fact.Results) {
+		return fact.Results[index] == model.AlwaysWrapping
+	}
+	return fact.Verdict == model.AlwaysWrapping
+}
+
+// isNilConst reports whether the value is the nil constant.
+func isNilConst(v ssa.Value) bool {
+	c, ok := v.(*ssa.Const)
+	return ok && c.IsNil()
+}
@@ -0,0 +1,216 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// stackCaptures are functions capturing stacktraces of their callers.
+var stackCaptures = map[string][]string{
+	"runtime":       {"Callers", "Caller", "Stack"},
+	"runtime/debug": {"Stack"},
+}
+
+// stackMethods are methods exposing stacktraces carried by errors, as in github.com/pkg/errors and similar libraries.
+var stackMethods = []string{"StackTrace", "Callers"}
+
+// isStackType reports whether errors of the type, or the type it points to, carry stacktraces:
+// the type is listed in StackTypes or has one of the stack methods.
+func (res *Result) isStackType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	if obj.Pkg() != nil && res.conf.StackTypes.Match(res.conf.PkgPath(obj.Pkg()), obj.Name()) {
+		return true
+	}
+	for _, name := range stackMethods {
+		if method, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, obj.Pkg(), name); method != nil {
+			if _, isFunc := method.(*types.Func); isFunc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStackCapture reports whether the function is one of the functions capturing stacktraces.
+func (res *Result) isStackCapture(obj *types.Func) bool {
+	if obj == nil || obj.Pkg() == nil {
+		return false
+	}
+	return slices.Contains(stackCaptures[res.conf.PkgPath(obj.Pkg())], obj.Name())
+}
+
+// capturesStack reports whether the function declaration or literal captures the stacktrace of its caller,
+// calling a stack capturing function directly or through functions declared in the package.
+// Closures created by the function are not searched.
+func (res *Result) capturesStack(info *model.Info, fn ast.Node) bool {
+	if captures, ok := res.captures[fn]; ok {
+		return captures
+	}
+	// Recursive calls capture nothing more
+	res.captures[fn] = false
+
+	var body *ast.BlockStmt
+	switch node := fn.(type) {
+	case *ast.FuncDecl:
+		body = node.Body
+	case *ast.FuncLit:
+		body = node.Body
+	}
+	captures := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if captures {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			var id *ast.Ident
+			switch fun := ast.Unparen(node.Fun).(type) {
+			case *ast.Ident:
+				id = fun
+			case *ast.SelectorExpr:
+				id = fun.Sel
+			}
+			obj, _ := info.Types.ObjectOf(id).(*types.Func)
+			if res.isStackCapture(obj) {
+				captures = true
+			} else if obj != nil && obj.Pkg() == info.Pkg {
+				decl := res.findFuncDecl(info, obj.Origin())
+				captures = decl != nil && res.capturesStack(info, decl)
+			}
+		}
+		return true
+	})
+	res.captures[fn] = captures
+	return captures
+}
+
+// isWrapper reports whether the function attaches stacktraces to the errors it returns:
+// it is listed in WrapperFunctions or inferred to be a wrapper.
+func (res *Result) isWrapper(fn *model.Function) bool {
+	if res.conf.WrapperFunctions.Match(fn.Pkg, fn.Name) {
+		return true
+	}
+	obj, ok := fn.Key.Object.(*types.Func)
+	return ok && res.isInferredWrapper(obj)
+}
+
+// isInferredWrapper reports whether the function returns errors and captures stacktraces, which makes it
+// a wrapper when InferWrappers is enabled. Functions of other packages are inferred from their facts.
+func (res *Result) isInferredWrapper(obj *types.Func) bool {
+	if !res.conf.InferWrappers || obj == nil || !hasErrorResult(obj.Signature()) {
+		return false
+	}
+	obj = obj.Origin()
+	if obj.Pkg() != res.pass.Pkg {
+		var fact WrappingFact
+		return res.pass.ImportObjectFact(obj, &fact) && fact.Inferred
+	}
+	info := model.NewInfo(res.pass)
+	decl := res.findFuncDecl(info, obj)
+	return decl != nil && res.capturesStack(info, decl)
+}
+
+// stackLiterals returns composite literals of errors carrying stacktraces: literals of stack types
+// and literals built by constructors capturing stacktraces. They are found once per package.
+func (res *Result) stackLiterals(info *model.Info) map[*ast.CompositeLit]bool {
+	if res.stackLits != nil {
+		return res.stackLits
+	}
+	res.stackLits = make(map[*ast.CompositeLit]bool)
+	for _, file := range info.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var body *ast.BlockStmt
+			switch fn := n.(type) {
+			case *ast.FuncDecl:
+				body = fn.Body
+			case *ast.FuncLit:
+				body = fn.Body
+			default:
+				return true
+			}
+			constructor := res.capturesStack(info, n)
+			ast.Inspect(body, func(m ast.Node) bool {
+				switch node := m.(type) {
+				case *ast.FuncLit:
+					// Closures are visited on their own
+					return false
+				case *ast.CompositeLit:
+					typ := info.Types.TypeOf(node)
+					if typ == nil || (!isErrorType(typ) && !isErrorType(types.NewPointer(typ))) {
+						return true
+					}
+					if constructor || res.isStackType(typ) {
+						log.Log("Literal %s carries a stacktrace\n", info.FormatNode(node.Type))
+						res.stackLits[node] = true
+					}
+				}
+				return true
+			})
+			return true
+		})
+	}
+	return res.stackLits
+}
+
+// allocCarriesStack reports whether the allocated value is an error carrying a stacktrace:
+// a value of a stack type or a value allocated by a constructor capturing stacktraces.
+func (e *SSAEngine) allocCarriesStack(alloc *ssa.Alloc) bool {
+	typ := alloc.Type().(*types.Pointer).Elem()
+	if types.IsInterface(typ) || (!isErrorType(typ) && !isErrorType(alloc.Type())) {
+		return false
+	}
+	if e.res.isStackType(typ) {
+		return true
+	}
+	for _, block := range alloc.Parent().Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			if callee := call.Common().StaticCallee(); callee != nil && e.capturesStack(callee, map[*ssa.Function]bool{}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// capturesStack reports whether the function is a stack capturing function or calls one,
+// directly or through functions of the package.
+func (e *SSAEngine) capturesStack(fn *ssa.Function, visited map[*ssa.Function]bool) bool {
+	if obj, ok := fn.Object().(*types.Func); ok && e.res.isStackCapture(obj) {
+		return true
+	}
+	if visited[fn] || fn.Pkg == nil || fn.Pkg.Pkg != e.pass.Pkg {
+		return false
+	}
+	visited[fn] = true
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+			if callee := call.Common().StaticCallee(); callee != nil && e.capturesStack(callee, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/AdamBrianBright/errstack/internal/config"
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// isUnwrap reports whether the function unwraps the error given as its first argument.
+func (res *Result) isUnwrap(fn *model.Function) bool {
+	return res.conf.UnwrapFunctions.Match(fn.Pkg, fn.Name)
+}
+
+// unwrappedVerdict returns the verdict of errors unwrapped from an error with the given verdict.
+func (res *Result) unwrappedVerdict(verdict model.Verdict) model.Verdict {
+	switch res.conf.UnwrapPolicy {
+	case config.UnwrapKeep:
+		return verdict
+	case config.UnwrapClean:
+		return model.Clean
+	}
+	return verdict.May()
+}
+
+// unwrapResult returns the verdict of the error returned by a call of an unwrap function.
+func (res *Result) unwrapResult(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	call *ast.CallExpr,
+	variables map[model.Place]model.Verdict,
+) model.Verdict {
+	if len(call.Args) == 0 {
+		return model.Clean
+	}
+	verdict := model.Clean
+	if result := res.analyzeCallStack(pass, cfgs, info, call.Args[0], variables); result != nil {
+		verdict = *result
+	}
+	return res.unwrappedVerdict(verdict)
+}
+
+// writeUnwrapTarget updates the target of an unwrap function storing the unwrapped error into its second argument,
+// e.g. errors.As(err, &target). Targets of stack types always carry stacktraces, other targets get the unwrapped error.
+// The target is updated whether or not the error was found, since it is only used where it was.
+func (res *Result) writeUnwrapTarget(
+	pass *analysis.Pass,
+	cfgs *ctrlflow.CFGs,
+	info *model.Info,
+	call *ast.CallExpr,
+	variables map[model.Place]model.Verdict,
+) {
+	if len(call.Args) < 2 || returnsError(info, call) {
+		return
+	}
+	fn := res.TryAddCallExpr(info, cfgs, call)
+	if fn == nil || !res.isUnwrap(fn) {
+		return
+	}
+	unary, ok := ast.Unparen(call.Args[1]).(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return
+	}
+	target := ast.Unparen(unary.X)
+	place, ok := placeOf(info, target)
+	if !ok || !isErrorType(info.Types.TypeOf(target)) {
+		return
+	}
+	verdict := model.AlwaysWrapping
+	if !res.isStackType(info.Types.TypeOf(target)) {
+		verdict = res.unwrapResult(pass, cfgs, info, call, variables)
+	}
+	log.Log("Function %s unwraps %s into %s\n", fn.Name, verdict, placeName(place))
+	storePlace(variables, place, verdict)
+}
+
+// isUnwrap reports whether the statically called function is one of the unwrap functions.
+func (e *SSAEngine) isUnwrap(call *ssa.CallCommon) bool {
+	pkg, name, ok := e.calleeName(call)
+	return ok && e.res.conf.UnwrapFunctions.Match(pkg, name)
+}
+
+// unwrapCarriesStack reports whether the error unwrapped by the call of an unwrap function may carry a stacktrace.
+func (e *SSAEngine) unwrapCarriesStack(call *ssa.CallCommon, visited map[ssa.Value]bool) bool {
+	return len(call.Args) > 0 &&
+		e.res.unwrappedVerdict(model.AlwaysWrapping).IsWrapping() &&
+		e.carriesStack(call.Args[0], visited)
+}
+
+// unwrapAlwaysCarriesStack reports whether the error unwrapped by the call of an unwrap function
+// carries a stacktrace on all paths.
+func (e *SSAEngine) unwrapAlwaysCarriesStack(call *ssa.CallCommon, visited map[ssa.Value]bool) bool {
+	return len(call.Args) > 0 &&
+		e.res.unwrappedVerdict(model.AlwaysWrapping) == model.AlwaysWrapping &&
+		e.alwaysCarriesStack(call.Args[0], visited)
+}
+
+// targetCarriesStack reports whether an unwrap function storing the unwrapped error into the target
+// the address is converted to may store an error with a stacktrace, e.g. errors.As(err, &target).
+func (e *SSAEngine) targetCarriesStack(target *ssa.MakeInterface, visited map[ssa.Value]bool) bool {
+	refs := target.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		call, ok := ref.(ssa.CallInstruction)
+		if !ok || !e.isUnwrap(call.Common()) {
+			continue
+		}
+		if args := call.Common().Args; len(args) > 1 && args[1] == target && e.unwrapCarriesStack(call.Common(), visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// isErrorTarget reports whether the type points to an error, as targets of unwrap functions do.
+func isErrorTarget(typ types.Type) bool {
+	ptr, ok := typ.Underlying().(*types.Pointer)
+	return ok && isErrorType(ptr.Elem())
+}
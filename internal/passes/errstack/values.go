@@ -0,0 +1,233 @@
+package errstack
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/AdamBrianBright/errstack/internal/log"
+	"github.com/AdamBrianBright/errstack/internal/model"
+
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+)
+
+// FuncValue is an expression assigned to a func-typed variable, struct field, map or slice.
+type FuncValue struct {
+	Info *model.Info
+	Expr ast.Expr
+}
+
+// TryAddFuncValue adds a virtual function for the func-typed variable and links it with all the functions
+// it may hold, so it is considered wrapping if any of them is.
+// Returns nil if the variable does not hold functions returning errors or none of its values are known.
+func (res *Result) TryAddFuncValue(info *model.Info, cfgs *ctrlflow.CFGs, node ast.Node, v *types.Var) *model.Function {
+	sig := funcSignature(v.Type())
+	if sig == nil || !hasErrors(sig) {
+		return nil
+	}
+	key := model.ObjectKey(v)
+	if fn, ok := res.FunctionsWithErrors[key]; ok {
+		if len(fn.Targets) == 0 {
+			return nil
+		}
+		return fn
+	}
+
+	fn := &model.Function{
+		Name:      v.Name(),
+		Key:       key,
+		Node:      node,
+		Type:      nil,
+		Body:      nil,
+		Block:     nil,
+		Pos:       info.Fset.Position(v.Pos()),
+		Verdict:   model.Clean,
+		Results:   nil,
+		Fields:    nil,
+		Callbacks: nil,
+		Yields:    nil,
+		Outs:      nil,
+		Wraps:     nil,
+		CalledBy:  model.Stack[*model.Function]{},
+		Targets:   model.Stack[*model.Function]{},
+		Partial:   false,
+		Pkg:       res.conf.PkgPath(v.Pkg()),
+		Info:      info,
+	}
+	res.FunctionsWithErrors[key] = fn
+
+	fn.Partial = !res.knowsAllValues(v)
+	res.indexFuncValues(info)
+	for _, value := range res.funcValues[key.Object] {
+		target := res.resolveFuncValue(value.Info, cfgs, value.Expr)
+		if target == nil {
+			continue
+		}
+		log.Log("Function value %s may hold %s: %s\n", fn.Name, target.Name, target.Pos.String())
+		// Calling the value returns whatever the held function returns
+		target.CalledBy.AddUnique(fn)
+		fn.Targets.AddUnique(target)
+	}
+	if len(fn.Targets) == 0 {
+		return nil
+	}
+	if fn.Partial {
+		// Values assigned by other packages are unknown callees
+		fn.Verdict = res.unknownVerdict()
+	}
+
+	return fn
+}
+
+// knowsAllValues reports whether indexFuncValues finds all values assigned to the func-typed variable or field.
+// Packages importing the analyzed one may assign its exported package variables and exported struct fields,
+// unless it is a main package, and variables of other packages are assigned outside of the analyzed files.
+func (res *Result) knowsAllValues(v *types.Var) bool {
+	if v.Pkg() != res.pass.Pkg {
+		return false
+	}
+	if res.pass.Pkg.Name() == "main" || !v.Exported() {
+		return true
+	}
+	return !v.IsField() && v.Parent() != v.Pkg().Scope()
+}
+
+// resolveFuncValue resolves an expression assigned to a func-typed value to a function.
+func (res *Result) resolveFuncValue(info *model.Info, cfgs *ctrlflow.CFGs, expr ast.Expr) *model.Function {
+	switch e := expr.(type) {
+	case *ast.FuncLit:
+		return res.TryAddFunction(info, cfgs, e)
+	case *ast.ParenExpr:
+		return res.resolveFuncValue(info, cfgs, e.X)
+	case *ast.CallExpr:
+		// Functions returned by sync.OnceValues and alike return what their argument returns
+		if arg := res.onceArgument(info, e); arg != nil {
+			return res.resolveFuncValue(info, cfgs, arg)
+		}
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr:
+		return res.TryAddCallExpr(info, cfgs, e)
+	}
+	return nil
+}
+
+// indexFuncValues collects all expressions assigned to func-typed values in the files of the given info.
+func (res *Result) indexFuncValues(info *model.Info) {
+	if res.indexedInfos[info.Types] {
+		return
+	}
+	res.indexedInfos[info.Types] = true
+
+	for _, file := range info.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if len(node.Lhs) != len(node.Rhs) {
+					return true
+				}
+				for i, lhs := range node.Lhs {
+					res.addFuncValue(info, referencedObject(info, lhs), node.Rhs[i])
+				}
+			case *ast.RangeStmt:
+				// Range values hold the elements of the ranged map, slice or array
+				if node.Value != nil {
+					res.addFuncValue(info, referencedObject(info, node.Value), node.X)
+				}
+			case *ast.ValueSpec:
+				for i, name := range node.Names {
+					if i < len(node.Values) {
+						res.addFuncValue(info, info.Types.ObjectOf(name), node.Values[i])
+					}
+				}
+			case *ast.CompositeLit:
+				typ := info.Types.TypeOf(node)
+				if typ == nil {
+					return true
+				}
+				st, ok := typ.Underlying().(*types.Struct)
+				if !ok {
+					return true
+				}
+				for i, elt := range node.Elts {
+					if kv, isKv := elt.(*ast.KeyValueExpr); isKv {
+						if key, isIdent := kv.Key.(*ast.Ident); isIdent {
+							res.addFuncValue(info, info.Types.ObjectOf(key), kv.Value)
+						}
+					} else if i < st.NumFields() {
+						res.addFuncValue(info, st.Field(i), elt)
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// addFuncValue records the expression as a possible value of the func-typed object.
+// Elements of map, slice and array literals are recorded as values of the object holding the literal.
+func (res *Result) addFuncValue(info *model.Info, obj types.Object, expr ast.Expr) {
+	if obj == nil || expr == nil || funcSignature(obj.Type()) == nil {
+		return
+	}
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		for _, elt := range lit.Elts {
+			if kv, isKv := elt.(*ast.KeyValueExpr); isKv {
+				elt = kv.Value
+			}
+			res.addFuncValue(info, obj, elt)
+		}
+		return
+	}
+
+	key := model.ObjectKey(obj).Object
+	for _, value := range res.funcValues[key] {
+		if value.Expr == expr {
+			return
+		}
+	}
+	log.Log("Function value %s is assigned %s\n", obj.Name(), info.FormatNode(expr))
+	res.funcValues[key] = append(res.funcValues[key], FuncValue{Info: info, Expr: expr})
+}
+
+// referencedObject returns the variable, field or container referenced by the expression.
+func referencedObject(info *model.Info, expr ast.Expr) types.Object {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return info.Types.ObjectOf(e)
+	case *ast.SelectorExpr:
+		return info.Types.ObjectOf(e.Sel)
+	case *ast.IndexExpr:
+		return referencedObject(info, e.X)
+	case *ast.IndexListExpr:
+		return referencedObject(info, e.X)
+	case *ast.StarExpr:
+		return referencedObject(info, e.X)
+	case *ast.ParenExpr:
+		return referencedObject(info, e.X)
+	}
+	return nil
+}
+
+// funcSignature returns the signature of a func type or of the elements of map, slice, array
+// and pointer of func types. Returns nil for any other type.
+func funcSignature(typ types.Type) *types.Signature {
+	return funcSignatureOf(typ, map[types.Type]bool{})
+}
+
+func funcSignatureOf(typ types.Type, visited map[types.Type]bool) *types.Signature {
+	if typ == nil || visited[typ] {
+		return nil
+	}
+	visited[typ] = true
+	switch t := typ.Underlying().(type) {
+	case *types.Signature:
+		return t
+	case *types.Map:
+		return funcSignatureOf(t.Elem(), visited)
+	case *types.Slice:
+		return funcSignatureOf(t.Elem(), visited)
+	case *types.Array:
+		return funcSignatureOf(t.Elem(), visited)
+	case *types.Pointer:
+		return funcSignatureOf(t.Elem(), visited)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testTransaction(context.Background())
+	_ = testTransactionClean(context.Background())
+	_ = testRetry()
+	_ = testFuncDecl()
+	_ = testMethodValue(Repo{})
+	_ = testTuple()
+	_ = testIgnored()
+	_ = testForwarded()
+}
+
+func withinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func testTransaction(ctx context.Context) error {
+	err := withinTransaction(ctx, func(ctx context.Context) error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testTransactionClean(ctx context.Context) error {
+	err := withinTransaction(ctx, func(ctx context.Context) error {
+		return fmt.Errorf("error")
+	})
+	return errors.Wrap(err, "wrapped")
+}
+
+func retry(attempts int, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func testRetry() error {
+	err := retry(3, func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func save() error {
+	return errors.New("error")
+}
+
+func testFuncDecl() error {
+	return errors.Wrap(retry(3, save), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+type Repo struct{}
+
+func (r Repo) Save() error { // want Save:"wrapping"
+	return errors.New("error")
+}
+
+func (r Repo) Clean() error { // want Clean:"clean"
+	return fmt.Errorf("error")
+}
+
+func testMethodValue(r Repo) error {
+	if err := retry(3, r.Clean); err != nil {
+		return errors.Wrap(err, "wrapped")
+	}
+	return errors.Wrap(retry(3, r.Save), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func query(fn func() (int, error)) (int, error) {
+	return fn()
+}
+
+func testTuple() error {
+	_, err := query(func() (int, error) {
+		return 0, errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func each(fn func() error) error {
+	_ = fn()
+	return fmt.Errorf("done")
+}
+
+func testIgnored() error {
+	err := each(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped")
+}
+
+func transaction(fn func() error) error {
+	return withinTransaction(context.Background(), func(ctx context.Context) error {
+		return fn()
+	})
+}
+
+func testForwarded() error {
+	err := transaction(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
@@ -11,6 +11,10 @@ func main() {
 	_ = testErrPointer()
 	_ = testErrShadow()
 	_ = testErrSecondVariable()
+	_ = testErrSiblingBranches(true)
+	_ = testErrMergedBranches(true)
+	_ = testErrElseBranch(true)
+	_ = testErrLoop(nil)
 }
 
 func testErrReassign() error {
@@ -25,7 +29,7 @@ func testErrPointer() error {
 		err = &err2
 	}
 	if err != nil {
-		return errors.Wrap(*err, "wrapped")
+		return errors.Wrap(*err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
 	}
 	return nil
 }
@@ -49,3 +53,41 @@ func testErrSecondVariable() error {
 	}
 	return nil
 }
+
+func testErrSiblingBranches(flag bool) error {
+	err := fmt.Errorf("error")
+	if flag {
+		err = errors.WithStack(err)
+		return err
+	}
+	return errors.Wrap(err, "wrapped")
+}
+
+func testErrMergedBranches(flag bool) error {
+	err := fmt.Errorf("error")
+	if flag {
+		err = errors.WithStack(err)
+	}
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testErrElseBranch(flag bool) error {
+	err := fmt.Errorf("error")
+	if flag {
+		return errors.Wrap(err, "wrapped")
+	} else {
+		err = errors.WithStack(err)
+	}
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testErrLoop(items []int) error {
+	var err error
+	for range items {
+		if err != nil {
+			return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+		}
+		err = errors.WithStack(fmt.Errorf("error"))
+	}
+	return nil
+}
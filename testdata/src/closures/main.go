@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testOnce()
+	_ = testOnceSometimes(true)
+	_ = testCalledVariable()
+	_ = testCalledLater()
+	_ = testImmediate()
+	_ = testNotCalled()
+	_ = testReset()
+	_ = testGoroutine()
+	_ = testWrappedInside()
+	_ = testNested()
+	_ = testRecursive([]*node{{}}, func(*node) error { return nil })
+	_ = testRecursiveCaptured(3)
+}
+
+func testOnce() error {
+	var once sync.Once
+	var err error
+	once.Do(func() {
+		err = errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testOnceSometimes(flag bool) error {
+	var mu sync.Mutex
+	err := fmt.Errorf("error")
+	withLock(&mu, func() {
+		if flag {
+			err = errors.WithStack(err)
+		}
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func withLock(mu *sync.Mutex, fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	fn()
+}
+
+func testCalledVariable() error {
+	var err error
+	load := func() {
+		err = errors.New("error")
+	}
+	load()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testCalledLater() error {
+	var err error
+	load := func() {
+		err = errors.New("error")
+	}
+	wrapped := errors.Wrap(err, "wrapped")
+	load()
+	_ = wrapped
+	return err
+}
+
+func testImmediate() error {
+	var err error
+	func() {
+		err = errors.New("error")
+	}()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testNotCalled() error {
+	err := fmt.Errorf("error")
+	load := func() {
+		err = errors.New("error")
+	}
+	_ = load
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testReset() error {
+	err := errors.New("error")
+	func() {
+		err = fmt.Errorf("error")
+	}()
+	return errors.Wrap(err, "wrapped")
+}
+
+func testGoroutine() error {
+	var wg sync.WaitGroup
+	var err error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err = errors.New("error")
+	}()
+	wg.Wait()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testWrappedInside() error {
+	var err error
+	func() {
+		cause := errors.New("error")
+		err = errors.Wrap(cause, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+	}()
+	return err
+}
+
+func testNested() error {
+	run := func() error {
+		err := errors.New("error")
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+	}
+	return run()
+}
+
+type node struct {
+	children []*node
+}
+
+func testRecursive(roots []*node, fn func(*node) error) error {
+	seen := make(map[*node]bool)
+	var visit func(n *node) error
+	visit = func(n *node) error {
+		if !seen[n] {
+			seen[n] = true
+			for _, child := range n.children {
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+			if err := fn(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var errs []error
+	for _, root := range roots {
+		if err := visit(root); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.WithStack(errs[0])
+	}
+	return nil
+}
+
+func testRecursiveCaptured(depth int) error {
+	var err error
+	var visit func(n int)
+	visit = func(n int) {
+		if n == 0 {
+			err = errors.New("error")
+			return
+		}
+		visit(n - 1)
+	}
+	visit(depth)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testField()
+	_ = testOtherField()
+	_ = testCompositeLit()
+	_ = testPointerLit()
+	_ = testNestedField()
+	_ = testSlice()
+	_ = testAppend()
+	_ = testRange()
+	_ = testMap()
+	_ = testMapLit()
+	_ = testReturnedStruct()
+	_ = testReturnedTuple()
+	_ = testCleanField()
+	_ = testFieldBranch(true)
+	_ = testArrayElement()
+	_ = testEmbeddedError()
+	_ = testEmbeddedErrorClean()
+}
+
+type Result struct {
+	Value int
+	Err   error
+	Warn  error
+}
+
+type Outer struct {
+	Inner Result
+}
+
+type wrappedError struct {
+	error
+}
+
+func testField() error {
+	var res Result
+	res.Err = errors.WithStack(fmt.Errorf("error"))
+	return errors.Wrap(res.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testOtherField() error {
+	var res Result
+	res.Err = errors.New("error")
+	res.Warn = fmt.Errorf("warning")
+	return errors.Wrap(res.Warn, "wrapped")
+}
+
+func testCompositeLit() error {
+	res := Result{Value: 1, Err: errors.New("error")}
+	return errors.Wrap(res.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testPointerLit() error {
+	res := &Result{Err: errors.New("error")}
+	return errors.Wrap(res.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testNestedField() error {
+	out := Outer{Inner: Result{Err: errors.New("error")}}
+	copied := out
+	return errors.Wrap(copied.Inner.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testSlice() error {
+	errs := []error{fmt.Errorf("error"), errors.New("error")}
+	return errors.Wrap(errs[0], "wrapped") // want `The error sometimes already has a stacktrace`
+}
+
+func testAppend() error {
+	var errs []error
+	errs = append(errs, errors.New("error"))
+	return errors.WithStack(errs[0]) // want `WithStack call unnecessarily wraps error with stacktrace`
+}
+
+func testRange() error {
+	errs := []error{errors.New("error")}
+	for _, err := range errs {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+	}
+	return nil
+}
+
+func testMap() error {
+	errs := map[string]error{}
+	errs["key"] = errors.New("error")
+	return errors.Wrap(errs["key"], "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testMapLit() error {
+	errs := map[string]error{"key": fmt.Errorf("error")}
+	return errors.Wrap(errs["key"], "wrapped")
+}
+
+func load() Result {
+	return Result{Err: errors.New("error"), Warn: fmt.Errorf("warning")}
+}
+
+func testReturnedStruct() error {
+	res := load()
+	_ = errors.Wrap(res.Warn, "wrapped")
+	return errors.Wrap(res.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func loadTuple() (*Result, error) {
+	res := &Result{}
+	res.Err = errors.New("error")
+	return res, nil
+}
+
+func testReturnedTuple() error {
+	res, err := loadTuple()
+	if err != nil {
+		return errors.Wrap(err, "wrapped")
+	}
+	return errors.Wrap(res.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testCleanField() error {
+	res := Result{Err: errors.New("error")}
+	res = Result{Err: fmt.Errorf("error")}
+	return errors.Wrap(res.Err, "wrapped")
+}
+
+func testFieldBranch(flag bool) error {
+	var res Result
+	if flag {
+		res.Err = errors.New("error")
+	}
+	return errors.Wrap(res.Err, "wrapped") // want `The error sometimes already has a stacktrace`
+}
+
+func testArrayElement() error {
+	var errs [2]error
+	errs[0] = errors.New("error")
+	return errors.WithStack(errs[1]) // want `The error sometimes already has a stacktrace`
+}
+
+func testEmbeddedError() error {
+	w := wrappedError{errors.New("error")}
+	return errors.WithStack(w.error) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testEmbeddedErrorClean() error {
+	w := &wrappedError{error: fmt.Errorf("error")}
+	return errors.WithStack(w.error)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"cross_package/repo"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testWrapDependencyMethod()
+	_ = testWrapDependencyCleanMethod()
+	_ = testWrapDependencyFunc()
+	_ = testWrapDependencyTransitive()
+	_ = testWrapDependencyInterface(repo.Repo{})
+	_ = testWrapDependencyResults()
+	_ = testWrapDependencyFields()
+	_ = testWrapDependencyCallback()
+	_ = testWrapDependencyIterator()
+	_ = testWrapDependencyVariable()
+	_ = testWrapDependencyOutParam()
+	_ = testWrapDependencyHelper()
+}
+
+func testWrapDependencyMethod() error {
+	err := repo.Repo{}.Get()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapDependencyCleanMethod() error {
+	err := repo.Repo{}.Clean()
+	return errors.Wrap(err, "wrapped")
+}
+
+func testWrapDependencyFunc() error {
+	_, err := repo.Load()
+	return errors.WithStack(err) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapDependencyTransitive() error {
+	return errors.Wrap(repo.Save(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapDependencyInterface(g repo.Getter) error {
+	return errors.Wrap(g.Get(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapDependencyResults() error {
+	warn, fatal := repo.Validate()
+	_ = errors.WithStack(fatal) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+	return errors.Wrap(warn, "wrapped")
+}
+
+func testWrapDependencyFields() error {
+	resp := repo.Fetch()
+	return errors.Wrap(resp.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapDependencyCallback() error {
+	err := repo.WithinTransaction(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testWrapDependencyIterator() error {
+	for _, err := range repo.Rows() {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+	}
+	return nil
+}
+
+func testWrapDependencyVariable() error {
+	return errors.Wrap(repo.ErrNotFound, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testWrapDependencyOutParam() error {
+	var err error
+	repo.Scan(&err)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testWrapDependencyHelper() error {
+	return repo.Wrap(errors.New("error")) // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/pkg/errors"
+)
+
+type Getter interface {
+	Get() error
+}
+
+type Repo struct{}
+
+func (r Repo) Get() error {
+	return errors.WithStack(fmt.Errorf("error"))
+}
+
+func (r Repo) Clean() error {
+	return fmt.Errorf("error")
+}
+
+func Load() (int, error) {
+	return 0, errors.New("error")
+}
+
+func Save() error {
+	return save()
+}
+
+func save() error {
+	return errors.Errorf("error")
+}
+
+func Validate() (warn error, fatal error) {
+	return fmt.Errorf("warning"), errors.New("fatal")
+}
+
+type Response struct {
+	Err error
+}
+
+func Fetch() Response {
+	return Response{Err: errors.New("error")}
+}
+
+func WithinTransaction(fn func() error) error {
+	if err := fn(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Rows() iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		yield(0, errors.New("error"))
+	}
+}
+
+var ErrNotFound = errors.New("not found")
+
+func Scan(dst *error) {
+	*dst = errors.New("error")
+}
+
+func Wrap(err error) error {
+	return errors.WithStack(err)
+}
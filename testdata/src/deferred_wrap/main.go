@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testDeferredDoubleWrap()
+	_ = testDeferredCleanWrap()
+	_ = testDeferredMixed(true)
+	_ = testCallerOfDeferred()
+	_, _ = testDeferredTuple()
+	_ = testCallerOfDeferredTuple()
+	_ = testDeferredWithMessage()
+}
+
+func testDeferredDoubleWrap() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "op") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+		}
+	}()
+	return errors.New("error")
+}
+
+func testDeferredCleanWrap() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "op")
+		}
+	}()
+	return fmt.Errorf("error")
+}
+
+func testDeferredMixed(flag bool) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "op") // want `The error sometimes already has a stacktrace`
+		}
+	}()
+	if flag {
+		return fmt.Errorf("error")
+	}
+	return errors.New("error")
+}
+
+func testCallerOfDeferred() error {
+	err := testDeferredCleanWrap()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testDeferredTuple() (n int, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.WithStack(err)
+		}
+	}()
+	if n > 0 {
+		return 0, nil
+	}
+	return 1, fmt.Errorf("error")
+}
+
+func testCallerOfDeferredTuple() error {
+	_, err := testDeferredTuple()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testDeferredWithMessage() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.WithMessage(err, "op")
+		}
+	}()
+	err = errors.New("error")
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
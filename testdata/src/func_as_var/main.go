@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
 func main() {
 	_ = testFuncAsVar()
+	_ = testWrapFuncAsVar()
+	_ = testWrapReassignedFuncAsVar()
+	_ = testWrapStructField(newServer())
+	_ = testWrapCleanStructField(Server{cleanHandler: handleClean})
+	_ = testWrapMapValue("wrapped")
+	_ = testWrapRegisteredMapValue("wrapped")
+	_ = testWrapSliceValue()
+	_ = testRecursiveTypes(Tree{}, RecursiveSlice{})
 }
 
 func testFuncAsVar() error {
@@ -16,3 +27,105 @@ func testFuncAsVar() error {
 
 	return err
 }
+
+func testWrapFuncAsVar() error {
+	f := func() error {
+		return errors.New("error")
+	}
+	err := f()
+
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapReassignedFuncAsVar() error {
+	f := func() error {
+		return fmt.Errorf("error")
+	}
+	f = returnsWrappedError
+
+	return errors.WithStack(f()) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func returnsWrappedError() error {
+	return errors.New("error")
+}
+
+func handle(_ context.Context) error {
+	return errors.New("error")
+}
+
+func handleClean(_ context.Context) error {
+	return fmt.Errorf("error")
+}
+
+type Server struct {
+	handler      func(ctx context.Context) error
+	cleanHandler func(ctx context.Context) error
+}
+
+func newServer() Server {
+	return Server{handler: handle}
+}
+
+func testWrapStructField(s Server) error {
+	err := s.handler(context.Background())
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapCleanStructField(s Server) error {
+	err := s.cleanHandler(context.Background())
+	return errors.Wrap(err, "wrapped")
+}
+
+var handlers = map[string]func(ctx context.Context) error{
+	"wrapped": handle,
+	"clean":   handleClean,
+}
+
+func testWrapMapValue(name string) error {
+	err := handlers[name](context.Background())
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+var registry = map[string]func(ctx context.Context) error{}
+
+func init() {
+	registry["clean"] = handleClean
+	registry["wrapped"] = func(ctx context.Context) error {
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func testWrapRegisteredMapValue(name string) error {
+	err := registry[name](context.Background())
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWrapSliceValue() error {
+	steps := []func(ctx context.Context) error{handleClean, handle}
+	for _, step := range steps {
+		if err := step(context.Background()); err != nil {
+			return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+		}
+	}
+	return nil
+}
+
+// Tree and RecursiveSlice refer to themselves, assigning them must not recurse through their element types forever
+type Tree map[string]Tree
+
+type RecursiveSlice []RecursiveSlice
+
+func testRecursiveTypes(t Tree, s RecursiveSlice) error {
+	var x Tree
+	x = t
+	var y RecursiveSlice
+	y = s
+	_, _ = x, y
+
+	f := func() error {
+		return errors.New("error")
+	}
+	g := f
+	return errors.Wrap(g(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
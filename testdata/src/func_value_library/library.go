@@ -0,0 +1,45 @@
+package func_value_library
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Loader may be reassigned by packages importing this one
+var Loader = func() error {
+	return fmt.Errorf("not found")
+}
+
+var loader = func() error {
+	return fmt.Errorf("not found")
+}
+
+// Client is configured by packages importing this one, they may set Fetch to anything
+type Client struct {
+	Fetch func() error
+	fetch func() error
+}
+
+func Load() error { // want Load:"always wrapping"
+	return errors.Wrap(Loader(), "load") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func LoadUnexported() error { // want LoadUnexported:"always wrapping"
+	return errors.Wrap(loader(), "load")
+}
+
+func NewClient() *Client {
+	return &Client{
+		Fetch: func() error { return fmt.Errorf("not found") },
+		fetch: func() error { return fmt.Errorf("not found") },
+	}
+}
+
+func (c *Client) Get() error { // want Get:"always wrapping"
+	return errors.Wrap(c.Fetch(), "get") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func (c *Client) GetUnexported() error { // want GetUnexported:"always wrapping"
+	return errors.Wrap(c.fetch(), "get")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testGenericFunction()
+	_ = testInferredGenericFunction()
+	_ = testMultipleTypeParams()
+	_ = testCleanMultipleTypeParams()
+	_ = testGenericReceiver()
+	_ = testCleanGenericReceiver()
+	_ = findWrapped(stackFinder{})
+	_ = findClean(cleanFinder{})
+	_ = findPointer(&pointerFinder{})
+	_ = findNested(stackFinder{})
+	_ = testGenericHolder()
+}
+
+type user struct{}
+
+func load[T any](v T) error {
+	return errors.Errorf("failed to load %v", v)
+}
+
+func testGenericFunction() error {
+	return errors.Wrap(load[int](1), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testInferredGenericFunction() error {
+	return errors.Wrap(load(1), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func get[T any, ID comparable](id ID) (T, error) {
+	var v T
+	return v, errors.Errorf("%v not found", id)
+}
+
+func getClean[T any, ID comparable](id ID) (T, error) {
+	var v T
+	return v, fmt.Errorf("%v not found", id)
+}
+
+func testMultipleTypeParams() error {
+	_, err := get[user, int](1)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testCleanMultipleTypeParams() error {
+	_, err := getClean[user, int](1)
+	return errors.Wrap(err, "wrapped")
+}
+
+type repo[T any, ID comparable] struct{}
+
+func (r *repo[T, ID]) find(id ID) (T, error) {
+	var v T
+	return v, errors.Errorf("%v not found", id)
+}
+
+func (r *repo[T, ID]) findClean(id ID) (T, error) {
+	var v T
+	return v, fmt.Errorf("%v not found", id)
+}
+
+func testGenericReceiver() error {
+	r := &repo[user, int]{}
+	_, err := r.find(1)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testCleanGenericReceiver() error {
+	r := &repo[user, int]{}
+	_, err := r.findClean(1)
+	return errors.Wrap(err, "wrapped")
+}
+
+type finder interface {
+	find() error
+}
+
+type stackFinder struct{}
+
+func (stackFinder) find() error {
+	return errors.New("error")
+}
+
+type cleanFinder struct{}
+
+func (cleanFinder) find() error {
+	return fmt.Errorf("error")
+}
+
+type pointerFinder struct{}
+
+func (*pointerFinder) find() error {
+	return errors.New("error")
+}
+
+// Only instantiated with stackFinder
+func findWrapped[F finder](f F) error {
+	return errors.Wrap(f.find(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+// Only instantiated with cleanFinder, so stackFinder and pointerFinder implementations don't matter
+func findClean[F finder](f F) error {
+	return errors.Wrap(f.find(), "wrapped")
+}
+
+func findPointer[F finder](f F) error {
+	return errors.WithStack(f.find()) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+// Instantiated with its own type parameter
+func findNested[G finder](g G) error {
+	return findPointerless(g)
+}
+
+func findPointerless[F finder](f F) error {
+	return errors.Wrap(f.find(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+type holder[F finder] struct {
+	finder F
+}
+
+func (h holder[F]) run() error {
+	return errors.Wrap(h.finder.find(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testGenericHolder() error {
+	return holder[stackFinder]{finder: stackFinder{}}.run()
+}
@@ -0,0 +1,39 @@
+// Package errgroup is a stub of golang.org/x/sync/errgroup for tests.
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+type Group struct {
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	return &Group{}, ctx
+}
+
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+func (g *Group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+			})
+		}
+	}()
+}
+
+func (g *Group) TryGo(f func() error) bool {
+	g.Go(f)
+	return true
+}
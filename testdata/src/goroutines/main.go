@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+func main() {
+	_ = testGroup()
+	_ = testGroupClean()
+	_ = testGroupMixed()
+	_ = testGroupWithContext(context.Background())
+	_ = testGroupFuncValue()
+	_ = testChannel()
+	_ = testChannelClean()
+	_ = testChannelRange()
+	_ = testChannelCommaOk()
+	_ = testOnceValues()
+	_ = testOnceValue()
+}
+
+func testGroup() error {
+	var g errgroup.Group
+	g.Go(func() error {
+		return errors.New("error")
+	})
+	return errors.WithStack(g.Wait()) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testGroupClean() error {
+	var g errgroup.Group
+	g.Go(func() error {
+		return fmt.Errorf("error")
+	})
+	return errors.WithStack(g.Wait())
+}
+
+func testGroupMixed() error {
+	var g errgroup.Group
+	g.Go(func() error {
+		return fmt.Errorf("error")
+	})
+	g.TryGo(func() error {
+		return errors.New("error")
+	})
+	return errors.WithStack(g.Wait()) // want `The error sometimes already has a stacktrace`
+}
+
+func testGroupWithContext(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return errors.WithStack(ctx.Err())
+	})
+	if err := g.Wait(); err != nil {
+		return errors.Wrap(err, "wait") // want `Wrap call unnecessarily wraps error with stacktrace`
+	}
+	return nil
+}
+
+func work() error {
+	return errors.New("error")
+}
+
+func testGroupFuncValue() error {
+	g := &errgroup.Group{}
+	g.Go(work)
+	return errors.WithStack(g.Wait()) // want `WithStack call unnecessarily wraps error with stacktrace`
+}
+
+func testChannel() error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- errors.New("error")
+	}()
+	err := <-errCh
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testChannelClean() error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fmt.Errorf("error")
+	}()
+	return errors.Wrap(<-errCh, "wrapped")
+}
+
+func testChannelRange() error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		errCh <- errors.New("error")
+	}()
+	for err := range errCh {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+	}
+	return nil
+}
+
+func testChannelCommaOk() error {
+	errCh := make(chan error, 1)
+	errCh <- errors.New("error")
+	if err, ok := <-errCh; ok {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+	}
+	return nil
+}
+
+func testOnceValues() error {
+	load := sync.OnceValues(func() (int, error) {
+		return 0, errors.New("error")
+	})
+	_, err := load()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace`
+}
+
+func testOnceValue() error {
+	check := sync.OnceValue(func() error {
+		return fmt.Errorf("error")
+	})
+	return errors.Wrap(check(), "wrapped")
+}
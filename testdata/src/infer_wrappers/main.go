@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"infer_wrappers/tracer"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testInferred()
+	_ = testInferredHelper()
+	_ = testInferredWraps()
+	_ = testInferredLocal()
+	_ = testInferredFormat()
+	_ = testNotInferred()
+}
+
+func dump(err error) error {
+	buf := make([]byte, 4096)
+	n := runtime.Stack(buf, false)
+	return fmt.Errorf("%w\n%s", err, buf[:n])
+}
+
+func testInferred() error {
+	err := tracer.Trace(fmt.Errorf("error"))
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testInferredHelper() error {
+	return errors.WithStack(tracer.Here(fmt.Errorf("error"))) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testInferredWraps() error {
+	return tracer.Trace(errors.New("error")) // want `Trace call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace\. No fix is suggested for Trace, list it in wrapperFunctions with replaceWith to get one`
+}
+
+func testInferredLocal() error {
+	return errors.Wrap(dump(fmt.Errorf("error")), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testInferredFormat() error {
+	return tracer.Tracef(errors.New("error"), "op %d", 1) // want `Tracef call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace\. No fix is suggested for Tracef, list it in wrapperFunctions with replaceWith to get one`
+}
+
+func testNotInferred() error {
+	return errors.Wrap(tracer.Message(fmt.Errorf("error"), "message"), "wrapped")
+}
@@ -0,0 +1,32 @@
+package tracer
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Trace attaches the stacktrace of the caller to the error without using any known wrapping library.
+func Trace(err error) error {
+	return fmt.Errorf("%w\n%s", err, debug.Stack())
+}
+
+// Here attaches the position of the caller to the error.
+func Here(err error) error {
+	return fmt.Errorf("%s: %w", caller(), err)
+}
+
+func caller() string {
+	_, file, line, _ := runtime.Caller(2)
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// Message captures nothing.
+func Message(err error, msg string) error {
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// Tracef attaches the stacktrace of the caller to the error along with a message.
+func Tracef(err error, format string, args ...any) error {
+	return fmt.Errorf("%s: %w\n%s", fmt.Sprintf(format, args...), err, debug.Stack())
+}
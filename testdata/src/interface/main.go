@@ -1,11 +1,17 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 )
 
 func main() {
 	_ = testInterface(Bar{Foo{}})
+	_ = testWrapInterface(Bar{Foo{}})
+	_ = testWrapCleanInterface(Baz{})
+	_ = testWrapMixedInterface(Baz{})
+	_ = testWrapPointerInterface(&Qux{})
 }
 
 type FooI interface {
@@ -14,7 +20,7 @@ type FooI interface {
 
 type Foo struct{}
 
-func (f Foo) Method() error {
+func (f Foo) Method() error { // want Method:"wrapping"
 	return errors.New("error")
 }
 
@@ -25,3 +31,56 @@ type Bar struct {
 func testInterface(bar Bar) error {
 	return bar.Foo.Method()
 }
+
+func testWrapInterface(bar Bar) error {
+	err := bar.Foo.Method()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+type CleanI interface {
+	Clean() error
+}
+
+type MixedI interface {
+	Mixed() (int, error)
+}
+
+type Baz struct{}
+
+func (b Baz) Clean() error { // want Clean:"clean"
+	return fmt.Errorf("error")
+}
+
+func (b Baz) Mixed() (int, error) { // want Mixed:"clean"
+	return 0, fmt.Errorf("error")
+}
+
+type Baq struct{}
+
+func (b Baq) Mixed() (int, error) { // want Mixed:"wrapping"
+	return 0, errors.WithStack(fmt.Errorf("error"))
+}
+
+func testWrapCleanInterface(c CleanI) error {
+	err := c.Clean()
+	return errors.Wrap(err, "wrapped")
+}
+
+func testWrapMixedInterface(m MixedI) error {
+	_, err := m.Mixed()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+type PointerI interface {
+	Pointer() error
+}
+
+type Qux struct{}
+
+func (q *Qux) Pointer() error { // want Pointer:"wrapping"
+	return errors.Errorf("error")
+}
+
+func testWrapPointerInterface(p PointerI) error {
+	return errors.WithStack(p.Pointer()) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
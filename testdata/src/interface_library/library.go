@@ -0,0 +1,92 @@
+package interface_library
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Store is implemented by memoryStore, packages importing this one may implement it as well
+type Store interface {
+	Load() error
+}
+
+type memoryStore struct{}
+
+func (memoryStore) Load() error { // want Load:"clean"
+	return fmt.Errorf("not found")
+}
+
+func NewStore() Store {
+	return memoryStore{}
+}
+
+func Load(s Store) error { // want Load:"always wrapping"
+	return errors.Wrap(s.Load(), "load") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func LoadKnown() error { // want LoadKnown:"always wrapping"
+	return errors.Wrap(memoryStore{}.Load(), "load")
+}
+
+// Saver is implemented by stackSaver, packages importing this one may implement it as well
+type Saver interface {
+	Save() error
+}
+
+type stackSaver struct{}
+
+func (stackSaver) Save() error { // want Save:"always wrapping"
+	return errors.New("error")
+}
+
+func NewSaver() Saver {
+	return stackSaver{}
+}
+
+func Save(s Saver) error { // want Save:"always wrapping"
+	return errors.Wrap(s.Save(), "save") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+// Validator is implemented by checker, packages importing this one may implement it as well
+type Validator interface {
+	Check() (warn error, fatal error)
+}
+
+type checker struct{}
+
+func (checker) Check() (warn error, fatal error) { // want Check:"wrapping"
+	return fmt.Errorf("warning"), errors.New("fatal")
+}
+
+func NewValidator() Validator {
+	return checker{}
+}
+
+func Check(v Validator) error { // want Check:"always wrapping"
+	warn, fatal := v.Check()
+	_ = errors.WithStack(fatal)         // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+	return errors.Wrap(warn, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+// Finder has an unexported method, so only memoryFinder and types embedding it implement it
+type Finder interface {
+	Find() error
+	finder()
+}
+
+type memoryFinder struct{}
+
+func (memoryFinder) Find() error { // want Find:"clean"
+	return fmt.Errorf("not found")
+}
+
+func (memoryFinder) finder() {}
+
+func NewFinder() Finder {
+	return memoryFinder{}
+}
+
+func Find(f Finder) error { // want Find:"always wrapping"
+	return errors.Wrap(f.Find(), "find")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testNamedIterator()
+	_ = testIteratorClosure()
+	_ = testCleanIterator()
+	_ = testMethodIterator(Rows{})
+	_ = testIteratorVariable()
+	_ = testSometimes()
+}
+
+func Items() iter.Seq2[int, error] { // want Items:"wrapping"
+	return func(yield func(int, error) bool) {
+		for i := 0; i < 3; i++ {
+			if !yield(i, errors.WithStack(fmt.Errorf("error"))) {
+				return
+			}
+		}
+	}
+}
+
+func testNamedIterator() error {
+	for _, err := range Items() {
+		if err != nil {
+			return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+		}
+	}
+	return nil
+}
+
+func testIteratorClosure() error {
+	items := func(yield func(int, error) bool) {
+		yield(0, errors.New("error"))
+	}
+	for _, err := range items {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+	}
+	return nil
+}
+
+func clean(yield func(int, error) bool) {
+	yield(0, fmt.Errorf("error"))
+}
+
+func testCleanIterator() error {
+	for _, err := range clean {
+		return errors.Wrap(err, "wrapped")
+	}
+	return nil
+}
+
+type Rows struct{}
+
+func (r Rows) All(yield func(error) bool) { // want All:"wrapping"
+	if !yield(nil) {
+		return
+	}
+	yield(errors.New("error"))
+}
+
+func testMethodIterator(r Rows) error {
+	for err := range r.All {
+		if err != nil {
+			return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+		}
+	}
+	return nil
+}
+
+func testIteratorVariable() error {
+	var err error
+	for _, err = range Items() {
+		break
+	}
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func mixed(yield func(int, error) bool) {
+	if !yield(0, fmt.Errorf("error")) {
+		return
+	}
+	yield(1, errors.New("error"))
+}
+
+func testSometimes() error {
+	for _, err := range mixed {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+	}
+	return nil
+}
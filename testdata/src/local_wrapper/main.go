@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"local_wrapper/stack"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testLocalWrapper()
+	_ = testLocalWrapperVariable()
+	_ = testLocalClean()
+}
+
+func testLocalWrapper() error {
+	return errors.Wrap(stack.Mark(fmt.Errorf("error")), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testLocalWrapperVariable() error {
+	err := stack.Mark(fmt.Errorf("error"))
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testLocalClean() error {
+	return errors.Wrap(stack.Clean("error"), "wrapped")
+}
@@ -0,0 +1,27 @@
+package stack
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+// Mark records the stacktrace without using any known wrapping library.
+func Mark(err error) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+// Clean is not listed in the config.
+func Clean(msg string) error {
+	return fmt.Errorf("%s", msg)
+}
@@ -32,3 +32,25 @@ func level5() error {
 	// But may be missed when MaxDepth limits the analysis depth
 	return errors.Wrap(errors.New("base error"), "wrapped") // want "Wrap call unnecessarily wraps error"
 }
+
+// The budget must not change the answer for chains longer than maxDepth
+
+func wrapLevel0() error {
+	return errors.Wrap(wrapLevel1(), "wrapped") // want "Wrap call unnecessarily wraps error"
+}
+
+func wrapLevel1() error {
+	return wrapLevel2()
+}
+
+func wrapLevel2() error {
+	return wrapLevel3()
+}
+
+func wrapLevel3() error {
+	return wrapLevel4()
+}
+
+func wrapLevel4() error {
+	return errors.New("base error")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testAlways()
+	_ = testSometimes(true)
+	_ = testSometimesVariable(true)
+	_ = testAlwaysVariable(true)
+}
+
+func alwaysWraps(c bool) error {
+	if c {
+		return errors.New("error")
+	}
+	return errors.Errorf("error")
+}
+
+func sometimesWraps(c bool) error {
+	if c {
+		return errors.New("error")
+	}
+	return fmt.Errorf("error")
+}
+
+func testAlways() error {
+	err := alwaysWraps(true)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testSometimes(c bool) error {
+	err := sometimesWraps(c)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testSometimesVariable(c bool) error {
+	err := fmt.Errorf("error")
+	if c {
+		err = errors.New("error")
+	}
+	return errors.Wrap(err, "wrapped") // want `The error sometimes already has a stacktrace`
+}
+
+func testAlwaysVariable(c bool) error {
+	err := errors.New("error")
+	if c {
+		err = errors.Errorf("error")
+	}
+	return errors.Wrap(err, "wrapped") // want `The error always has a stacktrace`
+}
@@ -13,10 +13,10 @@ func main() {
 
 type TestStruct struct{}
 
-func (t TestStruct) TestMethod() error {
+func (t TestStruct) TestMethod() error { // want TestMethod:"wrapping"
 	return errors.New("error")
 }
-func (t TestStruct) TestMethodUnwrapped() error {
+func (t TestStruct) TestMethodUnwrapped() error { // want TestMethodUnwrapped:"clean"
 	return fmt.Errorf("error")
 }
 
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testNested(3)
+	_ = testNestedClean(3)
+}
+
+func retry(n int, fn func() error) error {
+	var err error
+	for i := 0; i < n; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func transaction(fn func() error) error {
+	if err := fn(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// testNested nests closures in callbacks of higher-order functions twelve levels deep.
+func testNested(n int) error {
+	var last error
+	err := func() error {
+		return transaction(func() error {
+			return retry(n, func() error {
+				return transaction(func() error {
+					return retry(n, func() error {
+						return transaction(func() error {
+							return retry(n, func() error {
+								return transaction(func() error {
+									return retry(n, func() error {
+										return transaction(func() error {
+											return retry(n, func() error {
+												return transaction(func() error {
+													return retry(n, func() error {
+														for i := 0; i < n; i++ {
+															func() {
+																if i%2 == 0 {
+																	last = errors.New("error")
+																}
+															}()
+														}
+														return last
+													})
+												})
+											})
+										})
+									})
+								})
+							})
+						})
+					})
+				})
+			})
+		})
+	}()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testNestedClean(n int) error {
+	err := retry(n, func() error {
+		return transaction(func() error {
+			return retry(n, func() error {
+				return transaction(func() error {
+					return retry(n, func() error {
+						return transaction(func() error {
+							return fmt.Errorf("error")
+						})
+					})
+				})
+			})
+		})
+	})
+	return errors.Wrap(err, "wrapped")
+}
@@ -14,7 +14,7 @@ func main() {
 
 type Foo struct{}
 
-func (f Foo) Method() (int, error) {
+func (f Foo) Method() (int, error) { // want Method:"clean"
 	return 0, fmt.Errorf("error")
 }
 
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+var errCached = errors.New("cached")
+
+var errSentinel = fmt.Errorf("sentinel")
+
+var errLast error
+
+var ErrStacked = errors.New("stacked") // want ErrStacked:"always wrapping"
+
+func main() {
+	_ = testOutParam()
+	_ = testOutParamSometimes(true)
+	_ = testOutParamReset()
+	_ = testOutParamForwarded()
+	_ = testOutParamPointer()
+	_ = testOutParamKept()
+	_ = testGlobal()
+	_ = testGlobalSentinel()
+	_ = testGlobalLast()
+	_ = testGlobalAssigned()
+	_ = testExported()
+}
+
+func load(dst *error) {
+	*dst = errors.New("error")
+}
+
+func loadIf(dst *error, flag bool) {
+	if flag {
+		*dst = errors.New("error")
+	}
+}
+
+func reset(dst *error) {
+	*dst = nil
+}
+
+func loadForwarded(dst *error) {
+	load(dst)
+}
+
+func describe(dst *error) {
+	*dst = errors.WithMessage(*dst, "described")
+}
+
+func testOutParam() error {
+	var err error
+	load(&err)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testOutParamSometimes(flag bool) error {
+	err := fmt.Errorf("error")
+	loadIf(&err, flag)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testOutParamReset() error {
+	err := errors.New("error")
+	reset(&err)
+	return errors.Wrap(err, "wrapped")
+}
+
+func testOutParamForwarded() error {
+	var err error
+	loadForwarded(&err)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testOutParamPointer() error {
+	var err error
+	dst := &err
+	load(dst)
+	return errors.Wrap(*dst, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testOutParamKept() error {
+	err := errors.New("error")
+	describe(&err)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testGlobal() error {
+	return errors.Wrap(errCached, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testGlobalSentinel() error {
+	return errors.Wrap(errSentinel, "wrapped")
+}
+
+func remember() {
+	errLast = errors.New("error")
+}
+
+func testGlobalLast() error {
+	remember()
+	return errors.Wrap(errLast, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testGlobalAssigned() error {
+	errLast = fmt.Errorf("error")
+	return errors.Wrap(errLast, "wrapped")
+}
+
+func testExported() error {
+	return errors.WithStack(ErrStacked) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testSelfRecursion(3)
+	_ = testMutualRecursion(3)
+	_ = testCleanRecursion(3)
+	_ = testCycleTail(3)
+}
+
+func selfRecursive(n int) error {
+	if n == 0 {
+		return errors.New("error")
+	}
+	return selfRecursive(n - 1)
+}
+
+func testSelfRecursion(n int) error {
+	return errors.Wrap(selfRecursive(n), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func ping(n int) error {
+	if n == 0 {
+		return nil
+	}
+	return pong(n - 1)
+}
+
+func pong(n int) error {
+	if n == 0 {
+		return errors.Errorf("error")
+	}
+	return ping(n - 1)
+}
+
+func testMutualRecursion(n int) error {
+	return errors.Wrap(ping(n), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func cleanPing(n int) error {
+	if n == 0 {
+		return fmt.Errorf("error")
+	}
+	return cleanPong(n - 1)
+}
+
+func cleanPong(n int) error {
+	return cleanPing(n - 1)
+}
+
+func testCleanRecursion(n int) error {
+	return errors.Wrap(cleanPing(n), "wrapped")
+}
+
+// The cycle only reaches the wrapping function through its tail
+func cycleA(n int) error {
+	if n == 0 {
+		return cycleTail()
+	}
+	return cycleB(n - 1)
+}
+
+func cycleB(n int) error {
+	return cycleC(n - 1)
+}
+
+func cycleC(n int) error {
+	return cycleA(n - 1)
+}
+
+func cycleTail() error {
+	return errors.WithStack(fmt.Errorf("error"))
+}
+
+func testCycleTail(n int) error {
+	return errors.Wrap(cycleC(n), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
@@ -0,0 +1,15 @@
+package apperr
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Sanitize hides details of internal errors from clients.
+func Sanitize(err error) error {
+	return errors.New(err.Error())
+}
+
+// Describe adds a description to the error.
+func Describe(err error) error {
+	return errors.WithMessage(err, "described")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"reset_functions/apperr"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testReset()
+	_ = testResetLocal()
+	_ = testResetReturned()
+	_ = testClean()
+	_ = testResetWrapped()
+}
+
+func toStatus(err error) error {
+	return errors.Errorf("status: %s", err.Error())
+}
+
+func sanitized() error {
+	return apperr.Sanitize(errors.New("error"))
+}
+
+func testReset() error {
+	err := apperr.Sanitize(errors.New("error"))
+	return errors.Wrap(err, "wrapped")
+}
+
+func testResetLocal() error {
+	err := toStatus(errors.New("error"))
+	return errors.Wrap(err, "wrapped")
+}
+
+func testResetReturned() error {
+	return errors.WithStack(sanitized())
+}
+
+func testClean() error {
+	err := apperr.Describe(errors.New("error"))
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testResetWrapped() error {
+	err := fmt.Errorf("error")
+	return errors.Wrap(apperr.Sanitize(errors.WithStack(err)), "wrapped")
+}
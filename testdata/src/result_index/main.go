@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testWarn()
+	_ = testFatal()
+	_ = testDeclared()
+	_ = testValue()
+	_ = testForwarded()
+	_ = testInterface(Checker{})
+}
+
+func Validate() (warn error, fatal error) { // want Validate:"wrapping"
+	return fmt.Errorf("warning"), errors.New("fatal")
+}
+
+func testWarn() error {
+	warn, _ := Validate()
+	return errors.Wrap(warn, "wrapped")
+}
+
+func testFatal() error {
+	_, fatal := Validate()
+	return errors.Wrap(fatal, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testDeclared() error {
+	var warn, fatal = Validate()
+	_ = fatal
+	return errors.Wrap(warn, "wrapped")
+}
+
+func load() (int, error, error) {
+	return 0, errors.New("stacked"), fmt.Errorf("clean")
+}
+
+func testValue() error {
+	_, stacked, clean := load()
+	_ = errors.WithStack(stacked) // want `WithStack call unnecessarily wraps error with stacktrace`
+	return errors.Wrap(clean, "wrapped")
+}
+
+func forward() (error, error) {
+	return Validate()
+}
+
+func testForwarded() error {
+	warn, fatal := forward()
+	_ = errors.WithStack(fatal) // want `WithStack call unnecessarily wraps error with stacktrace`
+	return errors.Wrap(warn, "wrapped")
+}
+
+type ValidatorI interface {
+	Check() (warn error, fatal error)
+}
+
+type Checker struct{}
+
+func (c Checker) Check() (warn error, fatal error) { // want Check:"wrapping"
+	return fmt.Errorf("warning"), errors.New("fatal")
+}
+
+func testInterface(v ValidatorI) error {
+	warn, fatal := v.Check()
+	_ = errors.WithStack(fatal) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+	return errors.Wrap(warn, "wrapped")
+}
@@ -12,7 +12,7 @@ func main() {
 
 type Foo struct{}
 
-func (f Foo) Method() (int, error) {
+func (f Foo) Method() (int, error) { // want Method:"clean"
 	return 0, fmt.Errorf("error")
 }
 
@@ -20,7 +20,7 @@ type Bar struct {
 	Foo
 }
 
-func (f Bar) Method() error {
+func (f Bar) Method() error { // want Method:"clean"
 	_, err := f.Foo.Method()
 	return err
 }
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"runtime"
+	"sync"
+
+	"ssa_engine/results"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+func main() {
+	_ = testSSABranches(true)
+	_ = testSSASiblingBranches(true)
+	_ = testSSATuple()
+	_ = testSSAStructField()
+	_ = testSSAOtherStructField()
+	_ = testSSAClosure()
+	_ = testSSAClosureCall()
+	_ = testSSAShadow()
+	_ = testSSAPointer()
+	_ = testSSASlice()
+	_ = testSSARecursive(3)
+	_ = testSSAInterface(Repo{})
+	_ = testSSAClean()
+	_ = testSSAAlways(true)
+	_ = testSSAChannel()
+	_ = testSSAOnce()
+	_ = testSSAGroup()
+	_ = testSSACallback()
+	_ = testSSACallbackClean()
+	_ = testSSACallbackForwarded()
+	_ = testSSAIterator()
+	_ = testSSAIteratorClosure()
+	_ = testSSAIteratorClean()
+	_ = testSSAIteratorMethod(Repo{})
+	_ = testSSAOutParam()
+	_ = testSSAOutParamClean()
+	_ = testSSAGlobal()
+	_ = testSSAGlobalClean()
+	_ = testSSAStackType()
+	_ = testSSAStackConstructor()
+	_ = testSSAPlainType()
+	_ = testSSAHelper()
+	_ = testSSAHelperClean()
+	_ = testSSAReset()
+	_ = testSSACause()
+	_ = testSSACauseClean()
+	_ = testSSAAs()
+	_ = testSSAAsClean()
+	_ = testSSAResultWarn()
+	_ = testSSAResultFatal()
+	_ = testSSAResultForwarded()
+	_ = testSSADependencyWarn()
+	_ = testSSADependencyFatal()
+	_ = testSSAOutParamRewrapped()
+	_ = testSSAClosureRewrapped()
+	_ = testSSADeferRewrapped()
+}
+
+func testSSABranches(flag bool) error {
+	err := fmt.Errorf("error")
+	if flag {
+		err = errors.WithStack(err)
+	}
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testSSASiblingBranches(flag bool) error {
+	err := fmt.Errorf("error")
+	if flag {
+		err = errors.WithStack(err)
+		return err
+	}
+	return errors.Wrap(err, "wrapped")
+}
+
+func returnsTuple() (int, error) {
+	return 0, errors.New("error")
+}
+
+func testSSATuple() error {
+	_, err := returnsTuple()
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+type Result struct {
+	Err   error
+	Other error
+}
+
+func testSSAStructField() error {
+	var res Result
+	res.Err = errors.WithStack(fmt.Errorf("error"))
+	return errors.Wrap(res.Err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAOtherStructField() error {
+	res := &Result{}
+	res.Err = errors.WithStack(fmt.Errorf("error"))
+	res.Other = fmt.Errorf("error")
+	return errors.Wrap(res.Other, "wrapped")
+}
+
+func testSSAClosure() error {
+	var err error
+	func() {
+		err = errors.New("error")
+	}()
+	return errors.WithStack(err) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAClosureCall() error {
+	f := func() error {
+		return errors.New("error")
+	}
+	return errors.WithStack(f()) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAShadow() error {
+	err := errors.New("error")
+	_ = err
+	if err := fmt.Errorf("error"); err != nil {
+		return errors.Wrap(err, "wrapped")
+	}
+	return nil
+}
+
+func testSSAPointer() error {
+	var err error
+	p := &err
+	*p = errors.New("error")
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSASlice() error {
+	errs := []error{fmt.Errorf("error"), errors.New("error")}
+	return errors.Wrap(errs[0], "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSARecursive(n int) error {
+	if n == 0 {
+		return errors.New("error")
+	}
+	return errors.Wrap(testSSARecursive(n-1), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+type Getter interface {
+	Get() error
+}
+
+type Repo struct{}
+
+func (r Repo) Get() error { // want Get:"wrapping"
+	return errors.Errorf("error")
+}
+
+func testSSAInterface(g Getter) error {
+	return errors.Wrap(g.Get(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAClean() error {
+	err := fmt.Errorf("error")
+	return errors.Wrap(err, "wrapped")
+}
+
+func alwaysWraps(flag bool) error {
+	if flag {
+		return errors.New("error")
+	}
+	return errors.Errorf("error")
+}
+
+func testSSAAlways(flag bool) error {
+	err := alwaysWraps(flag)
+	return errors.Wrap(err, "wrapped") // want `The error always has a stacktrace`
+}
+
+func testSSAChannel() error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- errors.New("error")
+	}()
+	return errors.Wrap(<-errCh, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAOnce() error {
+	load := sync.OnceValue(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(load(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAGroup() error {
+	var g errgroup.Group
+	g.Go(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(g.Wait(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func withinTransaction(fn func() error) error {
+	if err := fn(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func testSSACallback() error {
+	err := withinTransaction(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSACallbackClean() error {
+	err := withinTransaction(func() error {
+		return fmt.Errorf("error")
+	})
+	return errors.Wrap(err, "wrapped")
+}
+
+func transaction(fn func() error) error {
+	return withinTransaction(func() error {
+		return fn()
+	})
+}
+
+func testSSACallbackForwarded() error {
+	err := transaction(func() error {
+		return errors.New("error")
+	})
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func items() iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		yield(0, errors.New("error"))
+	}
+}
+
+func testSSAIterator() error {
+	for _, err := range items() {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+	}
+	return nil
+}
+
+func testSSAIteratorClosure() error {
+	var last error
+	all := func(yield func(error) bool) {
+		yield(errors.New("error"))
+	}
+	for err := range all {
+		last = err
+	}
+	return errors.Wrap(last, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func cleanItems(yield func(int, error) bool) {
+	yield(0, fmt.Errorf("error"))
+}
+
+func testSSAIteratorClean() error {
+	for _, err := range cleanItems {
+		return errors.Wrap(err, "wrapped")
+	}
+	return nil
+}
+
+func (r Repo) All(yield func(error) bool) { // want All:"wrapping"
+	yield(errors.New("error"))
+}
+
+func testSSAIteratorMethod(r Repo) error {
+	for err := range r.All {
+		return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+	}
+	return nil
+}
+
+func loadSSA(dst *error) {
+	*dst = errors.New("error")
+}
+
+func loadSSAClean(dst *error) {
+	*dst = fmt.Errorf("error")
+}
+
+func testSSAOutParam() error {
+	var err error
+	loadSSA(&err)
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAOutParamClean() error {
+	var err error
+	loadSSAClean(&err)
+	return errors.Wrap(err, "wrapped")
+}
+
+var errSSACached = errors.New("cached")
+
+var errSSASentinel = fmt.Errorf("sentinel")
+
+func testSSAGlobal() error {
+	return errors.Wrap(errSSACached, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAGlobalClean() error {
+	return errors.Wrap(errSSASentinel, "wrapped")
+}
+
+type tracedSSAError struct {
+	trace []uintptr
+}
+
+func (e *tracedSSAError) Error() string {
+	return "traced"
+}
+
+func (e *tracedSSAError) StackTrace() []uintptr {
+	return e.trace
+}
+
+type plainSSAError struct {
+	pcs []uintptr
+}
+
+func (e *plainSSAError) Error() string {
+	return "plain"
+}
+
+func newTracedSSAError() error {
+	return &tracedSSAError{}
+}
+
+func newCallersSSAError() error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &plainSSAError{pcs: pcs[:n]}
+}
+
+func newPlainSSAError() error {
+	return &plainSSAError{}
+}
+
+func testSSAStackType() error {
+	return errors.Wrap(newTracedSSAError(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAStackConstructor() error {
+	return errors.Wrap(newCallersSSAError(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAPlainType() error {
+	return errors.Wrap(newPlainSSAError(), "wrapped")
+}
+
+func wrapSSA(err error, op string) error {
+	return errors.Wrapf(err, "%s", op)
+}
+
+func testSSAHelper() error {
+	return wrapSSA(errors.New("error"), "op") // want `wrapSSA call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSAHelperClean() error {
+	return wrapSSA(fmt.Errorf("error"), "op")
+}
+
+func sanitizeSSA(err error) error {
+	return errors.New(err.Error())
+}
+
+func testSSAReset() error {
+	err := sanitizeSSA(errors.New("error"))
+	return errors.Wrap(err, "wrapped")
+}
+
+func testSSACause() error {
+	err := errors.New("error")
+	return errors.WithStack(errors.Cause(err)) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testSSACauseClean() error {
+	return errors.WithStack(errors.Cause(fmt.Errorf("error")))
+}
+
+func testSSAAs() error {
+	err := errors.New("error")
+	var target *plainSSAError
+	if errors.As(err, &target) {
+		return errors.Wrap(target, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+	}
+	return nil
+}
+
+func testSSAAsClean() error {
+	err := fmt.Errorf("error")
+	var target *plainSSAError
+	if errors.As(err, &target) {
+		return errors.Wrap(target, "wrapped")
+	}
+	return nil
+}
+
+func validateSSA() (warn error, fatal error) {
+	return fmt.Errorf("warning"), errors.New("fatal")
+}
+
+func forwardSSA() (error, error) {
+	return validateSSA()
+}
+
+func testSSAResultWarn() error {
+	warn, _ := validateSSA()
+	return errors.Wrap(warn, "wrapped")
+}
+
+func testSSAResultFatal() error {
+	_, fatal := validateSSA()
+	return errors.Wrap(fatal, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testSSAResultForwarded() error {
+	warn, fatal := forwardSSA()
+	_ = errors.WithStack(fatal) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+	return errors.Wrap(warn, "wrapped")
+}
+
+func testSSADependencyWarn() error {
+	warn, _ := results.Validate()
+	return errors.Wrap(warn, "wrapped")
+}
+
+func testSSADependencyFatal() error {
+	_, fatal := results.Validate()
+	return errors.Wrap(fatal, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func cleanSSA() error {
+	return fmt.Errorf("error")
+}
+
+func testSSAOutParamRewrapped() error {
+	var err error
+	loadSSAClean(&err)
+	err = errors.Wrap(err, "wrapped")
+	return err
+}
+
+func testSSAClosureRewrapped() error {
+	err := cleanSSA()
+	do := func() {
+		err = errors.Wrap(err, "wrapped")
+	}
+	do()
+	return err
+}
+
+func testSSADeferRewrapped() (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrap(err, "wrapped")
+		}
+	}()
+	return cleanSSA()
+}
@@ -0,0 +1,11 @@
+package results
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func Validate() (warn error, fatal error) {
+	return fmt.Errorf("warning"), errors.New("fatal")
+}
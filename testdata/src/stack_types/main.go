@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+type AppError struct {
+	msg string
+}
+
+func (e *AppError) Error() string {
+	return e.msg
+}
+
+type tracedError struct {
+	msg   string
+	trace []uintptr
+}
+
+func (e tracedError) Error() string {
+	return e.msg
+}
+
+func (e tracedError) StackTrace() []uintptr {
+	return e.trace
+}
+
+type callersError struct {
+	msg string
+	pcs []uintptr
+}
+
+func (e *callersError) Error() string {
+	return e.msg
+}
+
+type plainError struct {
+	msg string
+}
+
+func (e *plainError) Error() string {
+	return e.msg
+}
+
+func main() {
+	_ = testConfigured()
+	_ = testConfiguredSometimes(true)
+	_ = testLiteral()
+	_ = testStackMethod()
+	_ = testConstructor()
+	_ = testConstructorHelper()
+	_ = testPlain()
+}
+
+func newAppError(msg string) error {
+	return &AppError{msg: msg}
+}
+
+func newAppErrorIf(flag bool) error {
+	if flag {
+		return &AppError{msg: "error"}
+	}
+	return fmt.Errorf("error")
+}
+
+func newTracedError(msg string) error {
+	return tracedError{msg: msg}
+}
+
+func newCallersError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &callersError{msg: msg, pcs: pcs[:n]}
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+func newCallersErrorHelper(msg string) error {
+	return &callersError{msg: msg, pcs: callers()}
+}
+
+func newPlainError(msg string) error {
+	return &plainError{msg: msg}
+}
+
+func testConfigured() error {
+	return errors.Wrap(newAppError("error"), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testConfiguredSometimes(flag bool) error {
+	return errors.Wrap(newAppErrorIf(flag), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testLiteral() error {
+	err := &AppError{msg: "error"}
+	return errors.WithStack(err) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testStackMethod() error {
+	return errors.Wrap(newTracedError("error"), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testConstructor() error {
+	return errors.Wrap(newCallersError("error"), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testConstructorHelper() error {
+	return errors.Wrap(newCallersErrorHelper("error"), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testPlain() error {
+	return errors.Wrap(newPlainError("error"), "wrapped")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testCallback(func() error { return nil })
+	_ = testPropagatedCallback(func() error { return nil })
+	_ = testInterface(nil)
+	_ = testStdlib()
+	_ = testKnownWrapping()
+	_ = testKnownClean()
+}
+
+func testCallback(cb func() error) error {
+	return errors.Wrap(cb(), "wrapped") // want `Wrap call wraps error of unknown origin, unable to tell whether it already has a stacktrace`
+}
+
+func callback(cb func() error) error {
+	return cb()
+}
+
+func testPropagatedCallback(cb func() error) error {
+	err := callback(cb)
+	return errors.WithStack(err) // want `WithStack call wraps error of unknown origin, unable to tell whether it already has a stacktrace`
+}
+
+// Loader has no implementations
+type Loader interface {
+	LoadUser() error
+}
+
+func testInterface(l Loader) error {
+	return errors.Wrap(l.LoadUser(), "wrapped") // want `Wrap call wraps error of unknown origin, unable to tell whether it already has a stacktrace`
+}
+
+func testStdlib() error {
+	_, err := strconv.Atoi("error")
+	return errors.Wrap(err, "wrapped")
+}
+
+func testKnownWrapping() error {
+	err := errors.New("error")
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testKnownClean() error {
+	err := fmt.Errorf("error")
+	return errors.Wrap(err, "wrapped")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testCallback(func() error { return nil })
+	_ = testStdlib()
+	_ = testKnownClean()
+}
+
+func testCallback(cb func() error) error {
+	return errors.Wrap(cb(), "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testStdlib() error {
+	_, err := strconv.Atoi("error")
+	return errors.Wrap(err, "wrapped")
+}
+
+func testKnownClean() error {
+	err := fmt.Errorf("error")
+	return errors.Wrap(err, "wrapped")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+type tracedError struct {
+	stack []uintptr
+}
+
+func (e *tracedError) Error() string {
+	return "traced"
+}
+
+func (e *tracedError) StackTrace() []uintptr {
+	return e.stack
+}
+
+type plainError struct{}
+
+func (e *plainError) Error() string {
+	return "plain"
+}
+
+func main() {
+	_ = testCause()
+	_ = testCauseClean()
+	_ = testUnwrap()
+	_ = testUnwrapStd()
+	_ = testAs()
+	_ = testAsStackType(fmt.Errorf("error"))
+	_ = testAsClean()
+}
+
+func testCause() error {
+	err := errors.New("error")
+	return errors.WithStack(errors.Cause(err)) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testCauseClean() error {
+	err := errors.WithMessage(fmt.Errorf("error"), "described")
+	return errors.WithStack(errors.Cause(err))
+}
+
+func testUnwrap() error {
+	err := errors.Wrap(fmt.Errorf("error"), "wrapped")
+	return errors.Wrap(errors.Unwrap(err), "unwrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testUnwrapStd() error {
+	err := fmt.Errorf("outer: %w", fmt.Errorf("error"))
+	return errors.Wrap(stderrors.Unwrap(err), "unwrapped")
+}
+
+func testAs() error {
+	err := errors.New("error")
+	var target *plainError
+	if errors.As(err, &target) {
+		return errors.Wrap(target, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+	}
+	return nil
+}
+
+func testAsStackType(err error) error {
+	var target *tracedError
+	if stderrors.As(err, &target) {
+		return errors.Wrap(target, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+	}
+	return nil
+}
+
+func testAsClean() error {
+	err := fmt.Errorf("outer: %w", &plainError{})
+	var target *plainError
+	if errors.As(err, &target) {
+		return errors.Wrap(target, "wrapped")
+	}
+	return nil
+}
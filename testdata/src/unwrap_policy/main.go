@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testCause()
+	_ = testCauseClean()
+	_ = testAs()
+}
+
+func testCause() error {
+	err := errors.New("error")
+	return errors.WithStack(errors.Cause(err)) // want `WithStack call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testCauseClean() error {
+	return errors.WithStack(errors.Cause(fmt.Errorf("error")))
+}
+
+func testAs() error {
+	err := errors.Wrap(fmt.Errorf("error"), "wrapped")
+	var target error
+	if errors.As(err, &target) {
+		return errors.Wrap(target, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func main() {
+	_ = testHelper()
+	_ = testHelperClean()
+	_ = testHelperNil()
+	_ = testHelperForwarded()
+	_ = testHelperResult()
+	_ = testHelperSometimes(true)
+	_ = testNotHelper()
+	_ = testNotAlwaysHelper(true)
+	_ = testHelperFormat()
+	_ = testHelperFormatForwarded([]any{1})
+	_ = testHelperShadowed()
+	_ = testHelperMessage()
+	_ = testHelperSideEffect()
+}
+
+func wrap(err error, op string) error {
+	return errors.Wrapf(err, "%s", op)
+}
+
+func wrapCode(err error) error {
+	return errors.Wrap(err, "code 500")
+}
+
+func logWrap(err error) error {
+	fmt.Println("wrapping", err)
+	return errors.WithStack(err)
+}
+
+func wrapNil(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.WithStack(err)
+}
+
+func wrapOp(op string, err error) error {
+	return wrap(err, op)
+}
+
+func wrapf(err error, format string, args ...any) error {
+	return errors.Wrapf(err, format, args...)
+}
+
+func annotate(err error, msg string) error {
+	return errors.WithMessage(err, msg)
+}
+
+func wrapIf(err error, flag bool) error {
+	if flag {
+		return errors.WithStack(err)
+	}
+	return err
+}
+
+func testHelper() error {
+	err := errors.New("error")
+	return wrap(err, "op") // want `wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testHelperClean() error {
+	return wrap(fmt.Errorf("error"), "op")
+}
+
+func testHelperNil() error {
+	return wrapNil(errors.New("error")) // want `wrapNil call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace\. No fix is suggested for wrapNil, list it in wrapperFunctions with replaceWith to get one`
+}
+
+func testHelperForwarded() error {
+	return wrapOp("op", errors.New("error")) // want `wrapOp call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace\. No fix is suggested for wrapOp, list it in wrapperFunctions with replaceWith to get one`
+}
+
+func testHelperResult() error {
+	err := wrap(fmt.Errorf("error"), "op")
+	return errors.Wrap(err, "wrapped") // want `Wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testHelperSometimes(flag bool) error {
+	err := fmt.Errorf("error")
+	if flag {
+		err = errors.New("error")
+	}
+	return wrap(err, "op") // want `wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error sometimes already has a stacktrace`
+}
+
+func testNotHelper() error {
+	return annotate(errors.New("error"), "message")
+}
+
+func testNotAlwaysHelper(flag bool) error {
+	return wrapIf(errors.New("error"), flag)
+}
+
+func testHelperFormat() error {
+	err := errors.New("error")
+	return wrapf(err, "op %d of %d", 1, 2) // want `wrapf call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testHelperFormatForwarded(args []any) error {
+	err := errors.New("error")
+	return wrapf(err, "op %d", args...) // want `wrapf call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testHelperShadowed() error {
+	errors := []error{stacked()}
+	return wrap(errors[0], "op") // want `wrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace\. No fix is suggested for wrap, list it in wrapperFunctions with replaceWith to get one`
+}
+
+func testHelperMessage() error {
+	return wrapCode(errors.New("error")) // want `wrapCode call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace`
+}
+
+func testHelperSideEffect() error {
+	return logWrap(errors.New("error")) // want `logWrap call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)\. The error always has a stacktrace\. No fix is suggested for logWrap, list it in wrapperFunctions with replaceWith to get one`
+}
+
+func stacked() error {
+	return errors.New("error")
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"example.com/repo"
+	"example.com/stack"
+)
+
+func main() {
+	_ = testWorkspaceModule()
+	_ = testWorkspaceModuleClean()
+	_ = testReplacedModule()
+	_ = testReplacedModuleClean()
+}
+
+func testWorkspaceModule() error {
+	err := repo.Find()
+	return stack.Trace(err) // want `Trace call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testWorkspaceModuleClean() error {
+	return stack.Trace(repo.Clean())
+}
+
+func testReplacedModule() error {
+	// New is not a listed wrapper, so its stacktraces are only known from facts of the replaced module
+	err := stack.New("not found")
+	return stack.Trace(err) // want `Trace call unnecessarily wraps error with stacktrace\. Replace with errors\.WithMessage\(\) or fmt\.Errorf\(\)`
+}
+
+func testReplacedModuleClean() error {
+	err := stack.Trace(repo.Clean())
+	return stack.Annotate(err, "annotated")
+}
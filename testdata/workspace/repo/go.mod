@@ -0,0 +1,7 @@
+module example.com/repo
+
+go 1.23.12
+
+require example.com/stack v0.0.0
+
+replace example.com/stack => ../stack
@@ -0,0 +1,17 @@
+package repo
+
+import (
+	"fmt"
+
+	"example.com/stack"
+)
+
+// Find returns errors with stacktraces recorded in the sibling module.
+func Find() error {
+	return stack.Trace(fmt.Errorf("not found"))
+}
+
+// Clean returns errors without stacktraces.
+func Clean() error {
+	return fmt.Errorf("not found")
+}
@@ -0,0 +1,32 @@
+package stack
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+// Trace records the stacktrace of the caller.
+func Trace(err error) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+// Annotate adds the message to the error without recording the stacktrace.
+func Annotate(err error, msg string) error {
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// New returns a new error with the stacktrace of the caller.
+func New(msg string) error {
+	return Trace(fmt.Errorf("%s", msg))
+}